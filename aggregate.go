@@ -0,0 +1,281 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// An AggFunc is an aggregation function.
+type AggFunc int
+
+// Valid aggregation functions.
+const (
+	// CountAgg is the number of records. If a field is defined, it is the
+	// number of records in which the field has content.
+	CountAgg AggFunc = iota
+
+	// SumAgg is the sum of the numeric values of the field.
+	SumAgg
+
+	// MinAgg is the minimum value of the field. Values are ordered as
+	// in NumericSort: values that are not decimal numbers are sorted as
+	// strings before the numbers.
+	MinAgg
+
+	// MaxAgg is the maximum value of the field.
+	MaxAgg
+
+	// MeanAgg is the arithmetic mean of the numeric values of the field.
+	MeanAgg
+
+	// DistinctAgg is the number of different values of the field.
+	DistinctAgg
+
+	// ConcatAgg is the concatenation of the different values of the field,
+	// in the order in which they were found.
+	ConcatAgg
+)
+
+var aggNames = []string{"count", "sum", "min", "max", "mean", "distinct", "concat"}
+
+func (a AggFunc) String() string {
+	if int(a) < 0 || int(a) >= len(aggNames) {
+		return "unknown"
+	}
+	return aggNames[a]
+}
+
+// ParseAggFunc returns the aggregation function with the given name.
+func ParseAggFunc(name string) (AggFunc, error) {
+	for i, n := range aggNames {
+		if strings.EqualFold(n, name) {
+			return AggFunc(i), nil
+		}
+	}
+	return 0, errors.Errorf("stanza: ParseAggFunc: unknown function %q", name)
+}
+
+// An Aggregate is an aggregation of the values of a field. If a field is
+// repeated in a record, all its values are used. Empty values are ignored.
+type Aggregate struct {
+	Func  AggFunc
+	Field string
+
+	// Name is the name of the field with the result. If empty, the
+	// name is the function name and the field, separated by '-' (e.g.
+	// "sum-population").
+	Name string
+
+	// Sep is the separator used by ConcatAgg. If empty, "; " is used.
+	Sep string
+}
+
+// name returns the name of the result field.
+func (a Aggregate) name() string {
+	if len(a.Name) > 0 {
+		return a.Name
+	}
+	if len(a.Field) == 0 {
+		return a.Func.String()
+	}
+	return a.Func.String() + "-" + a.Field
+}
+
+// A GroupBy is a reader that returns a record for each group of records
+// with the same values in the grouping fields. Each returned record has
+// the grouping fields, followed by the result of the aggregates. If a
+// grouping field is repeated in a record, only its first value is used. If
+// there are no grouping fields, a single record, with the aggregates of all
+// the records, is returned.
+//
+// All the records are read in the first call to ReadRecord. The groups are
+// returned in the order in which they were found.
+type GroupBy struct {
+	r       RecordReader
+	by      []string
+	aggs    []Aggregate
+	started bool
+	recs    []Record
+}
+
+// NewGroupBy returns a GroupBy that reads from r, groups the records by
+// the given fields, and calculates the given aggregates on each group.
+func NewGroupBy(r RecordReader, by []string, aggs ...Aggregate) *GroupBy {
+	return &GroupBy{r: r, by: by, aggs: aggs}
+}
+
+// Read reads the next group as a map.
+func (g *GroupBy) Read() (map[string]string, error) {
+	rec, err := g.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+	return rec.Map(), nil
+}
+
+// ReadRecord reads the next group.
+func (g *GroupBy) ReadRecord() (Record, error) {
+	if !g.started {
+		g.started = true
+		if err := g.group(); err != nil {
+			return nil, errors.Wrap(err, "stanza: GroupBy")
+		}
+	}
+	if len(g.recs) == 0 {
+		return nil, io.EOF
+	}
+	rec := g.recs[0]
+	g.recs = g.recs[1:]
+	return rec, nil
+}
+
+// group reads the records and calculates the aggregates.
+func (g *GroupBy) group() error {
+	type group struct {
+		key  []string
+		accs []*accumulator
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	for {
+		rec, err := g.r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		key := make([]string, len(g.by))
+		for i, f := range g.by {
+			key[i], _ = rec.Get(f)
+		}
+		k := strings.Join(key, "\x00")
+		gr, ok := index[k]
+		if !ok {
+			gr = &group{key: key}
+			for range g.aggs {
+				gr.accs = append(gr.accs, &accumulator{})
+			}
+			index[k] = gr
+			groups = append(groups, gr)
+		}
+		for i, a := range g.aggs {
+			gr.accs[i].add(rec, a)
+		}
+	}
+	if len(groups) == 0 && len(g.by) == 0 {
+		gr := &group{}
+		for range g.aggs {
+			gr.accs = append(gr.accs, &accumulator{})
+		}
+		groups = append(groups, gr)
+	}
+
+	for _, gr := range groups {
+		var rec Record
+		for i, f := range g.by {
+			rec = append(rec, Field{Name: f, Value: gr.key[i]})
+		}
+		for i, a := range g.aggs {
+			rec = append(rec, Field{Name: a.name(), Value: gr.accs[i].result(a)})
+		}
+		g.recs = append(g.recs, rec)
+	}
+	return nil
+}
+
+// An accumulator accumulates the values of an aggregate. Only the values
+// required by the aggregation function are kept.
+type accumulator struct {
+	count    int
+	n        int // number of numeric values
+	sum      float64
+	set      bool // min and max are set
+	min, max string
+	values   []string
+	distinct map[string]bool
+}
+
+// add adds the values of a record.
+func (acc *accumulator) add(rec Record, a Aggregate) {
+	if len(a.Field) == 0 {
+		acc.count++
+		return
+	}
+	found := false
+	for _, v := range rec.Values(a.Field) {
+		if len(v) == 0 {
+			continue
+		}
+		found = true
+		switch a.Func {
+		case SumAgg, MeanAgg:
+			if f, err := parseNumber(v); err == nil {
+				acc.n++
+				acc.sum += f
+			}
+		case MinAgg, MaxAgg:
+			if !acc.set {
+				acc.min, acc.max, acc.set = v, v, true
+				continue
+			}
+			if numericCompare(v, acc.min) < 0 {
+				acc.min = v
+			}
+			if numericCompare(v, acc.max) > 0 {
+				acc.max = v
+			}
+		case DistinctAgg, ConcatAgg:
+			if acc.distinct == nil {
+				acc.distinct = make(map[string]bool)
+			}
+			if !acc.distinct[v] {
+				acc.distinct[v] = true
+				acc.values = append(acc.values, v)
+			}
+		}
+	}
+	if found {
+		acc.count++
+	}
+}
+
+// result returns the result of an aggregate.
+func (acc *accumulator) result(a Aggregate) string {
+	switch a.Func {
+	case CountAgg:
+		return strconv.Itoa(acc.count)
+	case SumAgg:
+		return formatFloat(acc.sum)
+	case MinAgg:
+		return acc.min
+	case MaxAgg:
+		return acc.max
+	case MeanAgg:
+		if acc.n == 0 {
+			return ""
+		}
+		return formatFloat(acc.sum / float64(acc.n))
+	case DistinctAgg:
+		return strconv.Itoa(len(acc.distinct))
+	case ConcatAgg:
+		sep := a.Sep
+		if len(sep) == 0 {
+			sep = "; "
+		}
+		return strings.Join(acc.values, sep)
+	}
+	return ""
+}
+
+// formatFloat formats a number without exponent.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupBy(t *testing.T) {
+	data := `name: Puma concolor
+order: Carnivora
+mass: 60
+country: Argentina
+country: Chile
+%%
+name: Lycalopex culpaeus
+order: Carnivora
+mass: 9
+country: Chile
+%%
+name: Lama guanicoe
+order: Artiodactyla
+mass: 120
+country: Argentina
+%%
+name: Ozotoceros bezoarticus
+order: Artiodactyla
+%%
+`
+	g := NewGroupBy(NewReader(strings.NewReader(data)), []string{"order"},
+		Aggregate{Func: CountAgg},
+		Aggregate{Func: CountAgg, Field: "mass"},
+		Aggregate{Func: SumAgg, Field: "mass"},
+		Aggregate{Func: MinAgg, Field: "mass"},
+		Aggregate{Func: MaxAgg, Field: "mass"},
+		Aggregate{Func: MeanAgg, Field: "mass", Name: "mean"},
+		Aggregate{Func: DistinctAgg, Field: "country"},
+		Aggregate{Func: ConcatAgg, Field: "country", Sep: ","},
+	)
+	recs, err := readRecords(g)
+	if err != nil {
+		t.Fatalf("group by: %v", err)
+	}
+	want := []map[string]string{
+		{"order": "Carnivora", "count": "2", "count-mass": "2", "sum-mass": "69", "min-mass": "9", "max-mass": "60", "mean": "34.5", "distinct-country": "2", "concat-country": "Argentina,Chile"},
+		{"order": "Artiodactyla", "count": "2", "count-mass": "1", "sum-mass": "120", "min-mass": "120", "max-mass": "120", "mean": "120", "distinct-country": "1", "concat-country": "Argentina"},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("group by: found %d groups, want %d", len(recs), len(want))
+	}
+	for i, rec := range recs {
+		m := rec.Map()
+		if len(m) != len(want[i]) {
+			t.Errorf("group by: found %d fields, want %d", len(m), len(want[i]))
+		}
+		for f, v := range want[i] {
+			if m[f] != v {
+				t.Errorf("group by: %s: field %q: found %q, want %q", m["order"], f, m[f], v)
+			}
+		}
+	}
+}
+
+func TestAggregateMixed(t *testing.T) {
+	for _, data := range []string{
+		"v: Nan\n%%\nv: 3\n%%\nv: b\n%%\nv: 10\n%%\n",
+		"v: 10\n%%\nv: b\n%%\nv: 3\n%%\nv: Nan\n%%\n",
+	} {
+		g := NewGroupBy(NewReader(strings.NewReader(data)), nil,
+			Aggregate{Func: SumAgg, Field: "v"},
+			Aggregate{Func: MinAgg, Field: "v"},
+			Aggregate{Func: MaxAgg, Field: "v"},
+		)
+		recs, err := readRecords(g)
+		if err != nil {
+			t.Fatalf("aggregate mixed: %v", err)
+		}
+		if len(recs) != 1 {
+			t.Fatalf("aggregate mixed: found %d groups, want 1", len(recs))
+		}
+		m := recs[0].Map()
+		want := map[string]string{"sum-v": "13", "min-v": "Nan", "max-v": "10"}
+		for f, v := range want {
+			if m[f] != v {
+				t.Errorf("aggregate mixed: %q: field %q: found %q, want %q", data, f, m[f], v)
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPartialRecord is returned as the Err of a ParseError when a file ends
+// with an incomplete record.
+var ErrPartialRecord = errors.New("partial record at end of file")
+
+// An Appender appends records to the end of a file. Each record is written
+// with a single write, and committed to stable storage before Write or
+// WriteRecord returns, so a crash can only leave a partial record at the
+// end of the file.
+//
+// The embedded Writer defines the format of the written records. Writing
+// errors are reported as in a Writer.
+type Appender struct {
+	*Writer
+	name string
+	opt  FileOptions
+	f    *os.File
+	buf  bytes.Buffer
+}
+
+// OpenAppender opens the named file for appending using the default
+// options.
+func OpenAppender(name string) (*Appender, error) {
+	var opt FileOptions
+	return opt.OpenAppender(name)
+}
+
+// OpenAppender opens the named file for appending, creating the file if it
+// does not exist. The file should not be compressed. If Lock is true, the
+// file is locked while it is checked for a partial record, and while each
+// record is written.
+//
+// If the file ends with a partial record (i.e. a record without its end-
+// of-record mark, for example after a crash), and Repair is false, it
+// returns a ParseError with ErrPartialRecord, and the line in which the
+// partial record starts. If Repair is true, the partial record is removed.
+func (opt *FileOptions) OpenAppender(name string) (*Appender, error) {
+	f, err := os.OpenFile(name, appendFlag, 0666)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: OpenAppender")
+	}
+	a := &Appender{name: name, opt: *opt, f: f}
+	err = a.lock()
+	if err == nil {
+		err = opt.repair(a.f)
+		a.unlock()
+	}
+	if err != nil {
+		a.f.Close()
+		return nil, errors.Wrapf(err, "stanza: OpenAppender: %s", name)
+	}
+	a.Writer = NewWriter(&a.buf)
+	a.Writer.Dialect = opt.Dialect
+	if st, err := a.f.Stat(); err == nil && st.Size() > 0 {
+		a.Writer.started = true // no byte order mark
+	}
+	return a, nil
+}
+
+// appendFlag is the flag used to open an Appender file.
+const appendFlag = os.O_RDWR | os.O_CREATE | os.O_APPEND
+
+// lock locks the file of the appender, if required.
+func (a *Appender) lock() error {
+	if !a.opt.Lock {
+		return nil
+	}
+	var err error
+	a.f, err = a.opt.lockFile(a.name, a.f, appendFlag)
+	return err
+}
+
+// unlock unlocks the file of the appender, if required.
+func (a *Appender) unlock() {
+	if a.opt.Lock {
+		unlock(a.f)
+	}
+}
+
+// repair checks that the file does not end with a partial record.
+func (opt *FileOptions) repair(f *os.File) error {
+	end, line, nl, err := recordEnd(f, opt.Dialect)
+	if err != nil {
+		return err
+	}
+	if line > 0 {
+		if !opt.Repair {
+			return &ParseError{Line: line, Err: ErrPartialRecord}
+		}
+		if err := f.Truncate(end); err != nil {
+			return err
+		}
+		return f.Sync()
+	}
+	if nl {
+		if _, err := f.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordEnd returns the offset at the end of the last complete record of
+// r. If r ends with a partial record, line is the line in which the partial
+// record starts. If nl is true, a new line must be added before appending
+// a record (i.e. r does not end with a new line, or the last record of a
+// blank separated dialect is not followed by a blank line).
+func recordEnd(r io.Reader, d Dialect) (end int64, line int, nl bool, err error) {
+	blankSep := d.BlankSeparator && d.Terminator == 0
+	br := bufio.NewReader(r)
+	var off int64
+	for i := 1; ; i++ {
+		ln, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, 0, false, err
+		}
+		off += int64(len(ln))
+		switch {
+		case len(ln) == 0:
+		case len(strings.TrimSpace(ln)) == 0:
+			if blankSep && line > 0 {
+				end, line = off, 0
+			}
+		case !blankSep && isEnd(ln, d):
+			end, line = off, 0
+		case line > 0, isComment(ln, d):
+		default:
+			line = i
+		}
+		if err != io.EOF {
+			continue
+		}
+		if blankSep && line > 0 && len(ln) == 0 {
+			// the last record is complete, but without a separator
+			return off, 0, true, nil
+		}
+		return end, line, line == 0 && len(ln) > 0, nil
+	}
+}
+
+// isEnd returns true if ln is an end-of-record line.
+func isEnd(ln string, d Dialect) bool {
+	return strings.HasPrefix(ln, string(d.terminator()))
+}
+
+// isComment returns true if ln is a comment line.
+func isComment(ln string, d Dialect) bool {
+	for _, p := range d.comments() {
+		if strings.HasPrefix(ln, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write appends a single record to the file.
+func (a *Appender) Write(record map[string]string) error {
+	if err := a.Writer.Write(record); err != nil {
+		return err
+	}
+	return a.commit()
+}
+
+// WriteRecord appends a single record to the file.
+func (a *Appender) WriteRecord(record Record) error {
+	if err := a.Writer.WriteRecord(record); err != nil {
+		return err
+	}
+	return a.commit()
+}
+
+// commit writes the buffered record to the file.
+func (a *Appender) commit() error {
+	if err := a.Writer.Flush(); err != nil {
+		return err
+	}
+	defer a.buf.Reset()
+	if a.buf.Len() == 0 {
+		return nil
+	}
+	if err := a.lock(); err != nil {
+		// nothing was written, so the error is not sticky
+		return errors.Wrap(err, "stanza: Appender: Write")
+	}
+	defer a.unlock()
+	if _, err := a.f.Write(a.buf.Bytes()); err != nil {
+		a.Writer.err = errors.Wrap(err, "stanza: Appender: Write")
+		return a.Writer.err
+	}
+	if err := a.f.Sync(); err != nil {
+		a.Writer.err = errors.Wrap(err, "stanza: Appender: Write")
+		return a.Writer.err
+	}
+	return nil
+}
+
+// Close closes the file.
+func (a *Appender) Close() error {
+	err := a.Writer.Error()
+	if e := a.f.Close(); e != nil && err == nil {
+		err = errors.Wrap(e, "stanza: Appender: Close")
+	}
+	return err
+}
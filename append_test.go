@@ -0,0 +1,120 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestAppender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("appender: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "events.stz")
+	for _, ev := range []string{"start", "stop"} {
+		a, err := OpenAppender(name)
+		if err != nil {
+			t.Fatalf("appender: %v", err)
+		}
+		a.SetFields([]string{"event", "id"})
+		a.SetComputed("id", Counter(1))
+		if err := a.Write(map[string]string{"event": ev}); err != nil {
+			t.Errorf("appender: %v", err)
+		}
+		if err := a.Close(); err != nil {
+			t.Errorf("appender: %v", err)
+		}
+	}
+	recs, err := readFile(name)
+	if err != nil {
+		t.Fatalf("appender: %v", err)
+	}
+	if len(recs) != 2 || recs[1]["event"] != "stop" {
+		t.Fatalf("appender: found %v", recs)
+	}
+
+	// a crash while writing a record
+	f, _ := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0666)
+	f.WriteString("# a comment\r\nevent:\treload\r\n")
+	f.Close()
+	_, err = OpenAppender(name)
+	pe, ok := errors.Cause(err).(*ParseError)
+	if !ok || pe.Err != ErrPartialRecord || pe.Line != 8 {
+		t.Fatalf("appender: error %v, want %v at line 8", err, ErrPartialRecord)
+	}
+	a, err := (&FileOptions{Repair: true}).OpenAppender(name)
+	if err != nil {
+		t.Fatalf("appender: repair: %v", err)
+	}
+	a.Write(map[string]string{"event": "restart"})
+	a.Close()
+	recs, err = readFile(name)
+	if err != nil {
+		t.Fatalf("appender: repair: %v", err)
+	}
+	if len(recs) != 3 || recs[2]["event"] != "restart" {
+		t.Errorf("appender: repair: found %v", recs)
+	}
+
+	// a blank separated file without a final blank line
+	name = filepath.Join(dir, "status")
+	ioutil.WriteFile(name, []byte("package: a\n\npackage: b\n"), 0666)
+	a, err = (&FileOptions{Repair: true, Dialect: Debian}).OpenAppender(name)
+	if err != nil {
+		t.Fatalf("appender: debian: %v", err)
+	}
+	a.Write(map[string]string{"package": "c"})
+	a.Close()
+	f, _ = os.Open(name)
+	r := NewReader(f)
+	r.Dialect = Debian
+	recs, err = readAll(r)
+	f.Close()
+	if err != nil {
+		t.Fatalf("appender: debian: %v", err)
+	}
+	if len(recs) != 3 || recs[1]["package"] != "b" || recs[2]["package"] != "c" {
+		t.Errorf("appender: debian: found %v", recs)
+	}
+}
+
+func TestRecordEnd(t *testing.T) {
+	tests := []struct {
+		data string
+		d    Dialect
+		end  int64
+		line int
+		nl   bool
+	}{
+		{"", Dialect{}, 0, 0, false},
+		{"a: 1\n%%\n", Dialect{}, 8, 0, false},
+		{"a: 1\n%%", Dialect{}, 7, 0, true},
+		{"a: 1\n%%\n# comment\n\n", Dialect{}, 8, 0, false},
+		{"a: 1\n%%\nb: 2\n\tmore\n", Dialect{}, 8, 3, false},
+		{"a: 1\n%%\nb: 2", Dialect{}, 8, 3, false},
+		{"a: 1\n\nb: 2\n", Debian, 11, 0, true},
+		{"a: 1\n\nb: 2", Debian, 6, 3, false},
+		{"a: 1\n\nb: 2\n\n", Debian, 12, 0, false},
+	}
+	for _, test := range tests {
+		end, line, nl, err := recordEnd(strings.NewReader(test.data), test.d)
+		if err != nil {
+			t.Errorf("record end: %q: %v", test.data, err)
+			continue
+		}
+		if end != test.end || line != test.line || nl != test.nl {
+			t.Errorf("record end: %q: found %d, %d, %v, want %d, %d, %v", test.data, end, line, nl, test.end, test.line, test.nl)
+		}
+	}
+}
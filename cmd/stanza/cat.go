@@ -0,0 +1,72 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var catCmd = &command{
+	run:   runCat,
+	usage: "cat [-f <field>,...] [-lf] [-empty] [-spelling] [<file>...]",
+	short: "concatenate stanza files",
+	long: `
+Cat reads one or more stanza files and writes its records, as a single
+normalised stanza stream, on the standard output. If no file is given, the
+records are read from the standard input.
+
+Options are:
+
+	-f <field>,...
+	  Sets the list of fields to be printed, in the given order. By
+	  default all the fields are printed, in the order in which they
+	  were found in the input files.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+
+	-empty
+	  Print empty fields.
+
+	-spelling
+	  Write field names with the spelling found in the input files
+	  (e.g. "ISO3166" instead of "iso3166").
+	`,
+}
+
+var (
+	catOut   outFlags
+	catSpell spellFlag
+)
+
+func init() {
+	catOut.register(catCmd)
+	catSpell.register(catCmd)
+	add(catCmd)
+}
+
+func runCat(c *command, args []string) error {
+	var recs []map[string]string
+	var fields []string
+	for _, a := range inputs(args) {
+		r, f, err := openReader(a)
+		if err != nil {
+			return err
+		}
+		rs, err := readAll(r)
+		f.Close()
+		if err != nil {
+			return errors.Wrap(err, inputName(a))
+		}
+		recs = append(recs, rs...)
+		fields = addFields(fields, r.Fields())
+		catSpell.add(r)
+	}
+	w := catOut.writer(os.Stdout, fields)
+	catSpell.set(w)
+	return writeAll(w, recs)
+}
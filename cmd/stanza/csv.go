@@ -0,0 +1,121 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"os"
+
+	"github.com/js-arias/stanza/convert"
+	"github.com/pkg/errors"
+)
+
+var toCSVCmd = &command{
+	run:   runToCSV,
+	usage: "to-csv [-tab] [-f <field>,...] [<file>]",
+	short: "convert a stanza file into CSV",
+	long: `
+To-csv reads a stanza file and writes its records as CSV on the standard
+output. The first row is a header with the field names. If no file is
+given, the records are read from the standard input.
+
+Options are:
+
+	-tab
+	  Use tabs as separator (i.e. output a TSV file).
+
+	-f <field>,...
+	  Sets the list of fields (the columns) to be printed, in the given
+	  order. By default all the fields are printed, in the order in which
+	  they were found in the input file.
+	`,
+}
+
+var fromCSVCmd = &command{
+	run:   runFromCSV,
+	usage: "from-csv [-tab] [-f <field>,...] [-lf] [-empty] [<file>]",
+	short: "convert a CSV file into a stanza file",
+	long: `
+From-csv reads a CSV file and writes its rows as stanza records on the
+standard output. The first row of the CSV file must be a header with the
+field names. If no file is given, the rows are read from the standard
+input.
+
+Options are:
+
+	-tab
+	  Use tabs as separator (i.e. read a TSV file).
+
+	-f <field>,...
+	  Sets the list of fields to be printed, in the given order. By
+	  default all the fields are printed, in the order of the header.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+
+	-empty
+	  Print empty fields.
+	`,
+}
+
+var (
+	toCSVTab    bool
+	toCSVFields string
+	fromCSVTab  bool
+	fromCSVOut  outFlags
+)
+
+func init() {
+	toCSVCmd.flag.BoolVar(&toCSVTab, "tab", false, "")
+	toCSVCmd.flag.StringVar(&toCSVFields, "f", "", "")
+	add(toCSVCmd)
+
+	fromCSVCmd.flag.BoolVar(&fromCSVTab, "tab", false, "")
+	fromCSVOut.register(fromCSVCmd)
+	add(fromCSVCmd)
+}
+
+func runToCSV(c *command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	r, f, err := openReader(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(os.Stdout)
+	if toCSVTab {
+		w.Comma = '\t'
+	}
+	if err := convert.ToCSV(w, r, splitFields(toCSVFields)); err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
+
+func runFromCSV(c *command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	f, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	if fromCSVTab {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	if err := convert.FromCSV(fromCSVOut.writer(os.Stdout, nil), r); err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
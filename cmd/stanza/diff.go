@@ -0,0 +1,95 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var diffCmd = &command{
+	run:   runDiff,
+	usage: "diff -k <field> [-format <format>] [-lf] <old-file> <new-file>",
+	short: "compare the records of two stanza files",
+	long: `
+Diff compares the records of two stanza files, matching the records by the
+content of a key field, and prints the added and removed records, and the
+changed fields of the modified records, on the standard output. The order
+of the records and of the fields is ignored. If one of the files is "-",
+it is read from the standard input.
+
+Options are:
+
+	-k <field>
+	  Sets the key field. It is required.
+
+	-format <format>
+	  Sets the output format. Valid formats are 'unified' (the default),
+	  a human readable report similar to a unified diff; 'stanza', a
+	  stanza record for each changed field, with the fields "change",
+	  "key", "field", "old" and "new"; and 'json', a JSON array with an
+	  object for each changed record.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF, in the stanza format.
+	`,
+}
+
+var (
+	diffKey    string
+	diffFormat string
+	diffLF     bool
+)
+
+func init() {
+	diffCmd.flag.StringVar(&diffKey, "k", "", "")
+	diffCmd.flag.StringVar(&diffFormat, "format", "unified", "")
+	diffCmd.flag.BoolVar(&diffLF, "lf", false, "")
+	add(diffCmd)
+}
+
+func runDiff(c *command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("expecting two files")
+	}
+	key := splitFields(diffKey)
+	if len(key) != 1 {
+		return errors.New("expecting a key field")
+	}
+	from, ff, err := openReader(args[0])
+	if err != nil {
+		return err
+	}
+	defer ff.Close()
+	to, tf, err := openReader(args[1])
+	if err != nil {
+		return err
+	}
+	defer tf.Close()
+
+	changes, err := stanza.Diff(from, to, key[0])
+	if err != nil {
+		return err
+	}
+	switch diffFormat {
+	case "unified":
+		return stanza.WriteUnified(os.Stdout, changes, inputName(args[0]), inputName(args[1]))
+	case "stanza":
+		w := stanza.NewWriter(os.Stdout)
+		w.UseLF = diffLF
+		return stanza.WriteDiff(w, changes)
+	case "json":
+		if changes == nil {
+			changes = []stanza.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+	return errors.Errorf("unknown format %q", diffFormat)
+}
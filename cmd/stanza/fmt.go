@@ -0,0 +1,94 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var fmtCmd = &command{
+	run:   runFmt,
+	usage: "fmt [-w] [-f <field>,...] [-lf] [-empty] [-spelling] [<file>...]",
+	short: "format stanza files",
+	long: `
+Fmt reformats stanza files in their canonical form: field names in lower
+case (unless -spelling is used), aligned field contents, comments
+removed, and each record terminated with an end-of-record mark. By
+default the formatted files are printed on the standard output. If no
+file is given, the records are read from the standard input.
+
+Options are:
+
+	-w
+	  Write the result to the source file instead of the standard
+	  output.
+
+	-f <field>,...
+	  Sets the order of the fields. By default the fields are printed in
+	  the order in which they were found in the file. Fields not in the
+	  list will be removed.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+
+	-empty
+	  Print empty fields.
+
+	-spelling
+	  Keep the spelling of the field names found in the file (e.g.
+	  "ISO3166" instead of "iso3166").
+	`,
+}
+
+var (
+	fmtOut   outFlags
+	fmtSpell spellFlag
+	fmtWrite bool
+)
+
+func init() {
+	fmtOut.register(fmtCmd)
+	fmtSpell.register(fmtCmd)
+	fmtCmd.flag.BoolVar(&fmtWrite, "w", false, "")
+	add(fmtCmd)
+}
+
+func runFmt(c *command, args []string) error {
+	for _, a := range inputs(args) {
+		if err := formatFile(a); err != nil {
+			return errors.Wrap(err, inputName(a))
+		}
+	}
+	return nil
+}
+
+func formatFile(name string) error {
+	if fmtWrite && name != "-" {
+		return stanza.UpdateFile(name, format)
+	}
+	r, f, err := openReader(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return format(r, fmtOut.writer(os.Stdout, nil))
+}
+
+// format writes the records of r into w.
+func format(r *stanza.Reader, w *stanza.Writer) error {
+	recs, err := readAll(r)
+	if err != nil {
+		return err
+	}
+	fmtOut.setup(w, r.Fields())
+	// each file keeps its own spelling
+	fmtSpell.spell = nil
+	fmtSpell.add(r)
+	fmtSpell.set(w)
+	return writeAll(w, recs)
+}
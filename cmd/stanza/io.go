@@ -0,0 +1,199 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+// openInput opens an input file. If name is empty or "-", the standard
+// input is used.
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// openReader opens a stanza file for reading. Compressed files are
+// decompressed. If name is empty or "-", the standard input is used.
+func openReader(name string) (*stanza.Reader, io.Closer, error) {
+	if name == "" || name == "-" {
+		return stanza.NewReader(os.Stdin), ioutil.NopCloser(nil), nil
+	}
+	rc, err := stanza.OpenFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc.Reader, rc, nil
+}
+
+// inputName returns the name used in messages for an input file.
+func inputName(name string) string {
+	if name == "" || name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// inputs returns the list of input files. If no file is given, the
+// standard input is used.
+func inputs(args []string) []string {
+	if len(args) == 0 {
+		return []string{"-"}
+	}
+	return args
+}
+
+// splitFields splits a comma separated list of fields.
+func splitFields(list string) []string {
+	var fields []string
+	for _, f := range strings.Split(list, ",") {
+		f = strings.ToLower(strings.Join(strings.Fields(f), "-"))
+		if len(f) == 0 {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// readAll reads all the records from r.
+func readAll(r *stanza.Reader) ([]map[string]string, error) {
+	var recs []map[string]string
+	for {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// readRecords reads all the records from r.
+func readRecords(r stanza.RecordReader) ([]stanza.Record, error) {
+	var recs []stanza.Record
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// copyRecords writes all the records from r into w.
+func copyRecords(w *stanza.Writer, r stanza.RecordReader) error {
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := w.WriteRecord(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// addFields adds to a field list the fields not already in the list.
+func addFields(list, fields []string) []string {
+	ok := make(map[string]bool, len(list))
+	for _, f := range list {
+		ok[f] = true
+	}
+	for _, f := range fields {
+		if ok[f] {
+			continue
+		}
+		ok[f] = true
+		list = append(list, f)
+	}
+	return list
+}
+
+// outFlags are the flags used to define the output of a command.
+type outFlags struct {
+	fields string
+	lf     bool
+	empty  bool
+}
+
+// spellFlag is the flag used to keep the original spelling of field names.
+type spellFlag struct {
+	keep  bool
+	spell map[string]string
+}
+
+func (s *spellFlag) register(c *command) {
+	c.flag.BoolVar(&s.keep, "spelling", false, "")
+}
+
+// add adds the spelling of the fields read by r.
+func (s *spellFlag) add(r *stanza.Reader) {
+	if s.spell == nil {
+		s.spell = make(map[string]string)
+	}
+	for f, sp := range r.Spellings() {
+		if _, ok := s.spell[f]; !ok {
+			s.spell[f] = sp
+		}
+	}
+}
+
+// set sets the spelling of a writer, if the flag is set.
+func (s *spellFlag) set(w *stanza.Writer) {
+	if s.keep {
+		w.SetSpelling(s.spell)
+	}
+}
+
+func (o *outFlags) register(c *command) {
+	c.flag.StringVar(&o.fields, "f", "", "")
+	c.flag.BoolVar(&o.lf, "lf", false, "")
+	c.flag.BoolVar(&o.empty, "empty", false, "")
+}
+
+// writer returns a new stanza writer using the output flags. If no field
+// list was defined in the flags, fields is used as the field list.
+func (o *outFlags) writer(w io.Writer, fields []string) *stanza.Writer {
+	sw := stanza.NewWriter(w)
+	o.setup(sw, fields)
+	return sw
+}
+
+// setup sets a stanza writer using the output flags.
+func (o *outFlags) setup(w *stanza.Writer, fields []string) {
+	w.UseLF = o.lf
+	w.ForceEmpty = o.empty
+	if len(o.fields) > 0 {
+		fields = splitFields(o.fields)
+	}
+	w.SetFields(fields)
+}
+
+// writeAll writes all the records into w.
+func writeAll(w *stanza.Writer, recs []map[string]string) error {
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
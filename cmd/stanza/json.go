@@ -0,0 +1,112 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/js-arias/stanza/convert"
+	"github.com/pkg/errors"
+)
+
+var toJSONCmd = &command{
+	run:   runToJSON,
+	usage: "to-json [-nd] [-arrays] [<file>]",
+	short: "convert a stanza file into JSON",
+	long: `
+To-json reads a stanza file and writes its records as a JSON array of
+objects on the standard output. The members of each object are written in
+the order of the fields in the record. If no file is given, the records are
+read from the standard input.
+
+Options are:
+
+	-nd
+	  Write newline-delimited JSON objects, instead of an array.
+
+	-arrays
+	  Write the values of repeated fields as a JSON array. By default
+	  each value is written as a repeated member.
+	`,
+}
+
+var fromJSONCmd = &command{
+	run:   runFromJSON,
+	usage: "from-json [-f <field>,...] [-lf] [-empty] [<file>]",
+	short: "convert a JSON file into a stanza file",
+	long: `
+From-json reads a JSON array of objects, or a stream of JSON objects (e.g.
+newline-delimited JSON), and writes them as stanza records on the standard
+output. The elements of an array are written as repeated fields. If no
+file is given, the objects are read from the standard input.
+
+Options are:
+
+	-f <field>,...
+	  Sets the list of fields to be printed, in the given order. By
+	  default all the fields are printed, in the order of the object
+	  members.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+
+	-empty
+	  Print empty fields.
+	`,
+}
+
+var (
+	toJSONND     bool
+	toJSONArrays bool
+	fromJSONOut  outFlags
+)
+
+func init() {
+	toJSONCmd.flag.BoolVar(&toJSONND, "nd", false, "")
+	toJSONCmd.flag.BoolVar(&toJSONArrays, "arrays", false, "")
+	add(toJSONCmd)
+
+	fromJSONOut.register(fromJSONCmd)
+	add(fromJSONCmd)
+}
+
+func runToJSON(c *command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	r, f, err := openReader(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if toJSONND {
+		err = convert.ToNDJSON(os.Stdout, r, toJSONArrays)
+	} else {
+		err = convert.ToJSON(os.Stdout, r, toJSONArrays)
+	}
+	if err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
+
+func runFromJSON(c *command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	f, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := convert.FromJSON(fromJSONOut.writer(os.Stdout, nil), f); err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
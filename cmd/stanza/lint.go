@@ -0,0 +1,97 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var lintCmd = &command{
+	run:   runLint,
+	usage: "lint [<file>...]",
+	short: "report problems in stanza files",
+	long: `
+Lint reads stanza files and reports parsing problems, as well as problems
+found in strict mode: field names not in canonical form (lower case and
+without spaces), field contents without a field name, records without an
+end-of-record mark, invalid UTF-8 encoded characters, and invalid control
+characters. Each problem is reported as:
+
+	<file>:<line>: <message>
+
+or, if the problem is about a particular character:
+
+	<file>:<line>:<column>: <message>
+
+If no file is given, the records are read from the standard input.
+	`,
+}
+
+func init() {
+	add(lintCmd)
+}
+
+func runLint(c *command, args []string) error {
+	n := 0
+	for _, a := range inputs(args) {
+		p, err := lintFile(a)
+		n += p
+		if err != nil {
+			return errors.Wrap(err, inputName(a))
+		}
+	}
+	if n > 0 {
+		return errors.Errorf("%d problems found", n)
+	}
+	return nil
+}
+
+// lintFile reports the problems found in a file, and returns the number of
+// problems.
+func lintFile(name string) (int, error) {
+	r, f, err := openReader(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r.Strict = true
+	r.Check = stanza.Warn
+	n, nw := 0, 0
+	for {
+		_, err := r.Read()
+		for _, w := range r.Warnings()[nw:] {
+			printProblem(name, w.(*stanza.ParseError))
+			nw++
+			n++
+		}
+		if err == nil {
+			continue
+		}
+		if errors.Cause(err) == io.EOF {
+			return n, nil
+		}
+		pe, ok := errors.Cause(err).(*stanza.ParseError)
+		if !ok {
+			return n, err
+		}
+		printProblem(name, pe)
+		n++
+	}
+}
+
+// printProblem prints a problem found in a file.
+func printProblem(name string, pe *stanza.ParseError) {
+	if pe.Column > 0 {
+		fmt.Fprintf(os.Stdout, "%s:%d:%d: %v\n", inputName(name), pe.Line, pe.Column, pe.Err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "%s:%d: %v\n", inputName(name), pe.Line, pe.Err)
+}
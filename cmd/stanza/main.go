@@ -0,0 +1,118 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Stanza is a tool to manipulate stanza files.
+//
+// Usage:
+//
+//	stanza <command> [<argument>...]
+//
+// Use 'stanza help' for a list of commands, and 'stanza help <command>' for
+// information on a particular command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A command is a stanza subcommand.
+type command struct {
+	// run runs the command. The args are the arguments after the command
+	// name.
+	run func(c *command, args []string) error
+
+	// usage is the one-line usage message. The first word is the command
+	// name.
+	usage string
+
+	// short is the short description shown in 'stanza help'.
+	short string
+
+	// long is the long message shown in 'stanza help <command>'.
+	long string
+
+	// flag is the set of flags specific to the command.
+	flag flag.FlagSet
+}
+
+// name returns the command name.
+func (c *command) name() string {
+	name := c.usage
+	if i := strings.Index(name, " "); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (c *command) printUsage() {
+	fmt.Fprintf(os.Stderr, "usage: stanza %s\n\n", c.usage)
+	fmt.Fprintf(os.Stderr, "%s\n", strings.TrimSpace(c.long))
+	os.Exit(2)
+}
+
+// commands is the list of available commands.
+var commands = make(map[string]*command)
+
+// add adds a command to the command list.
+func add(c *command) {
+	c.flag.Usage = c.printUsage
+	commands[c.name()] = c
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
+	}
+	if args[0] == "help" {
+		help(args[1:])
+		return
+	}
+	c, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "stanza: unknown command %q\n", args[0])
+		fmt.Fprintf(os.Stderr, "run 'stanza help' for usage\n")
+		os.Exit(2)
+	}
+	c.flag.Parse(args[1:])
+	if err := c.run(c, c.flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "stanza: %s: %v\n", c.name(), err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Stanza is a tool to manipulate stanza files.\n\n")
+	fmt.Fprintf(os.Stderr, "Usage:\n\n\tstanza <command> [<argument>...]\n\n")
+	fmt.Fprintf(os.Stderr, "The commands are:\n")
+	var names []string
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "\t%-12s %s\n", n, commands[n].short)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse 'stanza help <command>' for more information about a command.\n")
+	os.Exit(2)
+}
+
+func help(args []string) {
+	if len(args) == 0 {
+		usage()
+	}
+	c, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "stanza: help: unknown command %q\n", args[0])
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stdout, "usage: stanza %s\n\n", c.usage)
+	fmt.Fprintf(os.Stdout, "%s\n", strings.TrimSpace(c.long))
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var sortCmd = &command{
+	run:   runSort,
+	usage: "sort [-k <field>[:<options>]]... [-locale <tag>] [-f <field>,...] [-lf] [-empty] [<file>]",
+	short: "sort the records of a stanza file",
+	long: `
+Sort reads a stanza file and writes its records, sorted by the content of
+one or more fields, on the standard output. Large files are sorted using
+temporary files. The sort is stable. If no file is given, the records are
+read from the standard input.
+
+Options are:
+
+	-k <field>[:<options>]
+	  Sets a field used to sort the records. It can be repeated to sort
+	  by several fields. Options is a set of letters: 'n' compares the
+	  values as numbers, 'v' compares sequences of digits as numbers
+	  (e.g. "a2" before "a10"), 'l' compares the values using the
+	  collation rules of the language set with -locale, and 'r'
+	  reverses the order. By default the values are compared as strings.
+
+	-locale <tag>
+	  Sets the language used by the 'l' option, as a BCP 47 tag (e.g.
+	  "es" or "de-AT").
+
+	-f <field>,...
+	  Sets the list of fields to be printed, in the given order. By
+	  default all the fields are printed, in the order of each record.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+
+	-empty
+	  Print empty fields.
+	`,
+}
+
+var (
+	sortOut    outFlags
+	sortKeys   keyFlag
+	sortLocale string
+)
+
+func init() {
+	sortOut.register(sortCmd)
+	sortCmd.flag.Var(&sortKeys, "k", "")
+	sortCmd.flag.StringVar(&sortLocale, "locale", "", "")
+	add(sortCmd)
+}
+
+func runSort(c *command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("too many arguments")
+	}
+	if len(sortKeys) == 0 {
+		return errors.New("expecting a sort key")
+	}
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	r, f, err := openReader(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for i := range sortKeys {
+		sortKeys[i].Locale = sortLocale
+	}
+	w := sortOut.writer(os.Stdout, nil)
+	if err := stanza.Sort(w, r, sortKeys...); err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
+
+// keyFlag is a list of sort keys.
+type keyFlag []stanza.SortKey
+
+func (k *keyFlag) String() string {
+	return ""
+}
+
+func (k *keyFlag) Set(v string) error {
+	field, opts := v, ""
+	if i := strings.LastIndex(v, ":"); i >= 0 {
+		field, opts = v[:i], v[i+1:]
+	}
+	key := stanza.SortKey{Field: strings.ToLower(strings.Join(strings.Fields(field), "-"))}
+	if len(key.Field) == 0 {
+		return errors.Errorf("invalid key %q", v)
+	}
+	for _, o := range opts {
+		switch o {
+		case 'n':
+			key.Mode = stanza.NumericSort
+		case 'v':
+			key.Mode = stanza.NaturalSort
+		case 'l':
+			key.Mode = stanza.LocaleSort
+		case 'r':
+			key.Desc = true
+		default:
+			return errors.Errorf("invalid key option %q", o)
+		}
+	}
+	*k = append(*k, key)
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var statsCmd = &command{
+	run:   runStats,
+	usage: "stats [-g <field>,...] [-a <function>:<field>]... [-lf] [<file>]",
+	short: "print statistics of a stanza file",
+	long: `
+Stats reads a stanza file and prints, as stanza records on the standard
+output, a summary of each field: its inferred type, the fraction of records
+with the field (fill-rate), the number of distinct values, and for numeric
+fields, the minimum, maximum, mean and sum of the values. If no file is
+given, the records are read from the standard input.
+
+If aggregate functions are defined with -a, it prints a record for each
+group of records with the same values of the fields given with -g, with
+the result of the aggregates.
+
+Options are:
+
+	-g <field>,...
+	  Sets the fields used to group the records.
+
+	-a <function>[:<field>]
+	  Adds an aggregate function. It can be repeated. Valid functions
+	  are 'count', 'sum', 'min', 'max', 'mean', 'distinct' and
+	  'concat'. The field is required except for 'count'.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+	`,
+}
+
+var (
+	statsGroup string
+	statsAggs  aggFlag
+	statsLF    bool
+)
+
+func init() {
+	statsCmd.flag.StringVar(&statsGroup, "g", "", "")
+	statsCmd.flag.Var(&statsAggs, "a", "")
+	statsCmd.flag.BoolVar(&statsLF, "lf", false, "")
+	add(statsCmd)
+}
+
+func runStats(c *command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("too many arguments")
+	}
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	r, f, err := openReader(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := stanza.NewWriter(os.Stdout)
+	w.UseLF = statsLF
+	if len(statsAggs) > 0 {
+		g := stanza.NewGroupBy(r, splitFields(statsGroup), statsAggs...)
+		if err := copyRecords(w, g); err != nil {
+			return errors.Wrap(err, inputName(name))
+		}
+		return w.Flush()
+	}
+
+	recs, err := readRecords(r)
+	if err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	if err := fieldStats(w, recs); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// fieldStats writes the summary of each field.
+func fieldStats(w *stanza.Writer, recs []stanza.Record) error {
+	l := recordList(recs)
+	s, err := stanza.InferSchema(&l)
+	if err != nil {
+		return err
+	}
+	var aggs []stanza.Aggregate
+	for _, f := range s.Fields {
+		if f.Type != stanza.Integer && f.Type != stanza.Float {
+			continue
+		}
+		for _, fn := range []stanza.AggFunc{stanza.MinAgg, stanza.MaxAgg, stanza.MeanAgg, stanza.SumAgg} {
+			aggs = append(aggs, stanza.Aggregate{Func: fn, Field: f.Name})
+		}
+	}
+	l = recordList(recs)
+	num, err := stanza.NewGroupBy(&l, nil, aggs...).ReadRecord()
+	if err != nil {
+		return err
+	}
+
+	w.SetFields([]string{"field", "type", "fill-rate", "distinct", "min", "max", "mean", "sum"})
+	for _, f := range s.Fields {
+		rec := stanza.Record{
+			{Name: "field", Value: f.Name},
+			{Name: "type", Value: f.Type.String()},
+			{Name: "fill-rate", Value: strconv.FormatFloat(f.Presence, 'g', 3, 64)},
+			{Name: "distinct", Value: strconv.Itoa(f.Cardinality)},
+		}
+		for _, a := range []string{"min", "max", "mean", "sum"} {
+			if v, ok := num.Get(a + "-" + f.Name); ok {
+				rec = append(rec, stanza.Field{Name: a, Value: v})
+			}
+		}
+		if err := w.WriteRecord(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggFlag is a list of aggregates.
+type aggFlag []stanza.Aggregate
+
+func (a *aggFlag) String() string {
+	return ""
+}
+
+func (a *aggFlag) Set(v string) error {
+	name, field := v, ""
+	if i := strings.Index(v, ":"); i >= 0 {
+		name, field = v[:i], v[i+1:]
+	}
+	fn, err := stanza.ParseAggFunc(name)
+	if err != nil {
+		return errors.Errorf("invalid function %q", name)
+	}
+	field = strings.ToLower(strings.Join(strings.Fields(field), "-"))
+	if len(field) == 0 && fn != stanza.CountAgg {
+		return errors.Errorf("function %q without field", name)
+	}
+	*a = append(*a, stanza.Aggregate{Func: fn, Field: field})
+	return nil
+}
+
+// recordList is a list of records read as a stanza.RecordReader.
+type recordList []stanza.Record
+
+func (l *recordList) ReadRecord() (stanza.Record, error) {
+	if len(*l) == 0 {
+		return nil, io.EOF
+	}
+	rec := (*l)[0]
+	*l = (*l)[1:]
+	return rec, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/js-arias/stanza/convert"
+	"github.com/pkg/errors"
+)
+
+var toTableCmd = &command{
+	run:   runToTable,
+	usage: "to-table [-f <field>,...] [<file>]",
+	short: "convert a stanza file into a NoSQL table",
+	long: `
+To-table reads a stanza file and writes its records as a NoSQL table on
+the standard output. It is equivalent to the NoSQL 'listtotable' operator.
+If no file is given, the records are read from the standard input.
+
+Options are:
+
+	-f <field>,...
+	  Sets the list of fields (the columns) to be printed, in the given
+	  order. By default all the fields are printed, in the order in which
+	  they were found in the input file.
+	`,
+}
+
+var fromTableCmd = &command{
+	run:   runFromTable,
+	usage: "from-table [-f <field>,...] [-lf] [-empty] [<file>]",
+	short: "convert a NoSQL table into a stanza file",
+	long: `
+From-table reads a NoSQL table and writes its rows as stanza records on the
+standard output. It is equivalent to the NoSQL 'tabletolist' operator. If
+no file is given, the table is read from the standard input.
+
+Options are:
+
+	-f <field>,...
+	  Sets the list of fields to be printed, in the given order. By
+	  default all the fields are printed, in the order of the table
+	  columns.
+
+	-lf
+	  Use LF as line terminator, instead of CR-LF.
+
+	-empty
+	  Print empty fields.
+	`,
+}
+
+var (
+	toTableFields string
+	fromTableOut  outFlags
+)
+
+func init() {
+	toTableCmd.flag.StringVar(&toTableFields, "f", "", "")
+	add(toTableCmd)
+
+	fromTableOut.register(fromTableCmd)
+	add(fromTableCmd)
+}
+
+func runToTable(c *command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	r, f, err := openReader(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := convert.ListToTable(os.Stdout, r, splitFields(toTableFields)); err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
+
+func runFromTable(c *command, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	f, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := convert.TableToList(fromTableOut.writer(os.Stdout, nil), f); err != nil {
+		return errors.Wrap(err, inputName(name))
+	}
+	return nil
+}
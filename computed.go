@@ -0,0 +1,73 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Compute is a function that returns the content of a field derived from
+// the content of a record.
+type Compute func(record map[string]string) string
+
+// Constant returns a Compute that always returns v.
+func Constant(v string) Compute {
+	return func(map[string]string) string {
+		return v
+	}
+}
+
+// Concat returns a Compute that returns the concatenation of the non-empty
+// content of the given fields, separated by sep.
+func Concat(sep string, fields ...string) Compute {
+	return func(record map[string]string) string {
+		var vs []string
+		for _, f := range fields {
+			if v := strings.TrimSpace(record[f]); len(v) > 0 {
+				vs = append(vs, v)
+			}
+		}
+		return strings.Join(vs, sep)
+	}
+}
+
+// Counter returns a Compute that returns an increasing integer, starting at
+// start.
+func Counter(start int) Compute {
+	n := start
+	return func(map[string]string) string {
+		v := strconv.Itoa(n)
+		n++
+		return v
+	}
+}
+
+// UUID returns a Compute that returns a random (version 4) UUID.
+func UUID() Compute {
+	return func(map[string]string) string {
+		var u [16]byte
+		if _, err := rand.Read(u[:]); err != nil {
+			return ""
+		}
+		u[6] = (u[6] & 0x0f) | 0x40
+		u[8] = (u[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+	}
+}
+
+// Timestamp returns a Compute that returns the current time formatted with
+// the given layout. If layout is empty, time.RFC3339 will be used.
+func Timestamp(layout string) Compute {
+	if len(layout) == 0 {
+		layout = time.RFC3339
+	}
+	return func(map[string]string) string {
+		return time.Now().Format(layout)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/stanza"
+)
+
+var blob = `
+Name:	República Argentina
+Common:	Argentina
+Population: 42669500
+Anthem:	Ya su trono dignísimo abrieron
+	las Provincias Unidas del Sud
+%%
+Name:	中华人民共和国
+Common:	China
+Population: 1339724852
+%%
+`
+
+// records returns the records of a stanza blob.
+func records(t *testing.T, data string) []map[string]string {
+	recs, err := readAll(stanza.NewReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("reading error: %v", err)
+	}
+	return recs
+}
+
+// compare compares two lists of records.
+func compare(t *testing.T, name string, got, want []map[string]string) {
+	if len(got) != len(want) {
+		t.Fatalf("%s: found %d records, want %d", name, len(got), len(want))
+	}
+	for i, w := range want {
+		if len(got[i]) != len(w) {
+			t.Errorf("%s: record %d: found %d fields, want %d", name, i, len(got[i]), len(w))
+		}
+		for f, v := range w {
+			if got[i][f] != v {
+				t.Errorf("%s: record %d: field %q: found %q, want %q", name, i, f, got[i][f], v)
+			}
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	for _, comma := range []rune{',', '\t'} {
+		out := &bytes.Buffer{}
+		cw := csv.NewWriter(out)
+		cw.Comma = comma
+		if err := ToCSV(cw, stanza.NewReader(strings.NewReader(blob)), nil); err != nil {
+			t.Fatalf("csv: %v", err)
+		}
+		if h := strings.SplitN(out.String(), "\n", 2)[0]; h != strings.Join([]string{"name", "common", "population", "anthem"}, string(comma)) {
+			t.Errorf("csv: header %q", h)
+		}
+
+		st := &bytes.Buffer{}
+		cr := csv.NewReader(out)
+		cr.Comma = comma
+		if err := FromCSV(stanza.NewWriter(st), cr); err != nil {
+			t.Fatalf("csv: %v", err)
+		}
+		compare(t, "csv", records(t, st.String()), records(t, blob))
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data := "name:\tArgentina\nborder: Chile\nborder: Bolivia\n%%\nname:\tPeru\nborder: Chile\n%%\n"
+	for _, arrays := range []bool{false, true} {
+		for _, nd := range []bool{false, true} {
+			out := &bytes.Buffer{}
+			var err error
+			if nd {
+				err = ToNDJSON(out, stanza.NewReader(strings.NewReader(data)), arrays)
+			} else {
+				err = ToJSON(out, stanza.NewReader(strings.NewReader(data)), arrays)
+			}
+			if err != nil {
+				t.Fatalf("json: %v", err)
+			}
+			if strings.Contains(out.String(), `"border":["Chile","Bolivia"]`) != arrays {
+				t.Errorf("json: arrays %v: output %s", arrays, out.String())
+			}
+
+			st := &bytes.Buffer{}
+			sw := stanza.NewWriter(st)
+			sw.UseLF = true
+			if err := FromJSON(sw, out); err != nil {
+				t.Fatalf("json: %v", err)
+			}
+			if st.String() != data {
+				t.Errorf("json: arrays %v, nd %v: found\n%s", arrays, nd, st.String())
+			}
+		}
+	}
+}
+
+func TestNoSQLTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := ListToTable(out, stanza.NewReader(strings.NewReader(blob)), nil); err != nil {
+		t.Fatalf("table: %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if lines[0] != "name\tcommon\tpopulation\tanthem" {
+		t.Errorf("table: header %q", lines[0])
+	}
+	if lines[1] != "----\t------\t----------\t------" {
+		t.Errorf("table: ruler %q", lines[1])
+	}
+
+	st := &bytes.Buffer{}
+	if err := TableToList(stanza.NewWriter(st), out); err != nil {
+		t.Fatalf("table: %v", err)
+	}
+	compare(t, "table", records(t, st.String()), records(t, blob))
+
+	// the ruler counts characters, not bytes
+	out.Reset()
+	if err := ListToTable(out, stanza.NewReader(strings.NewReader("país: Chile\n%%\n")), nil); err != nil {
+		t.Fatalf("table: %v", err)
+	}
+	if lines := strings.Split(out.String(), "\n"); lines[1] != "----" {
+		t.Errorf("table: ruler %q, want %q", lines[1], "----")
+	}
+}
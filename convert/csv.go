@@ -0,0 +1,169 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package convert converts stanza records from and to other formats.
+package convert
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+// ToCSV reads all the records from r and writes them into w as CSV rows.
+// The first row is a header with the field names. If fields is empty, the
+// fields returned by r.Fields are used as the header, and all the records
+// are read before any row is written. Fields not in the header are
+// ignored.
+//
+// To write TSV files, set the Comma of w to '\t'.
+func ToCSV(w *csv.Writer, r *stanza.Reader, fields []string) error {
+	next, fields, err := source(r, fields)
+	if err != nil {
+		return errors.Wrap(err, "convert: ToCSV")
+	}
+
+	if err := w.Write(fields); err != nil {
+		return errors.Wrap(err, "convert: ToCSV: writing header")
+	}
+	row := make([]string, len(fields))
+	for {
+		rec, err := next()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "convert: ToCSV")
+		}
+		for i, f := range fields {
+			row[i] = rec[f]
+		}
+		if err := w.Write(row); err != nil {
+			return errors.Wrap(err, "convert: ToCSV")
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, "convert: ToCSV")
+	}
+	return nil
+}
+
+// FromCSV reads CSV rows from r and writes them into w as stanza records.
+// The first row must be a header with the field names. Field names are
+// stored in lower case, with spaces replaced by '-'. If w does not have a
+// field list, the fields of the header, in the header order, will be used
+// as the field list of w.
+//
+// To read TSV files, set the Comma of r to '\t'.
+func FromCSV(w *stanza.Writer, r *csv.Reader) error {
+	head, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return errors.Wrap(err, "convert: FromCSV: reading header")
+	}
+	fields, err := header(head)
+	if err != nil {
+		return errors.Wrap(err, "convert: FromCSV")
+	}
+	if len(w.Fields()) == 0 {
+		var fl []string
+		for _, f := range fields {
+			if len(f) > 0 {
+				fl = append(fl, f)
+			}
+		}
+		w.SetFields(fl)
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "convert: FromCSV")
+		}
+		rec := make(map[string]string, len(fields))
+		for i, v := range row {
+			if i >= len(fields) || len(fields[i]) == 0 {
+				continue
+			}
+			rec[fields[i]] = v
+		}
+		if err := w.Write(rec); err != nil {
+			return errors.Wrap(err, "convert: FromCSV")
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "convert: FromCSV")
+	}
+	return nil
+}
+
+// header returns the field names of a header row. Empty columns are
+// returned as empty strings.
+func header(row []string) ([]string, error) {
+	fields := make([]string, len(row))
+	ok := make(map[string]bool)
+	for i, h := range row {
+		f := fieldName(h)
+		if len(f) == 0 {
+			continue
+		}
+		if ok[f] {
+			return nil, errors.Errorf("duplicated field %q in header", f)
+		}
+		ok[f] = true
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// fieldName returns a field name in its canonical form.
+func fieldName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// source returns a function that returns the records from r, and the list
+// of fields to be written. If fields is empty, all the records are read, and
+// r.Fields is used as the list of fields.
+func source(r *stanza.Reader, fields []string) (next func() (map[string]string, error), fl []string, err error) {
+	if len(fields) > 0 {
+		return r.Read, fields, nil
+	}
+	recs, err := readAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	next = func() (map[string]string, error) {
+		if len(recs) == 0 {
+			return nil, io.EOF
+		}
+		rec := recs[0]
+		recs = recs[1:]
+		return rec, nil
+	}
+	return next, r.Fields(), nil
+}
+
+// readAll reads all the records from r.
+func readAll(r *stanza.Reader) ([]map[string]string, error) {
+	var recs []map[string]string
+	for {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+// ToJSON reads all the records from r and writes them into w as a JSON
+// array of objects. The members of each object are written in the order of
+// the fields in the record. If arrays is true, the values of a repeated
+// field are written as a JSON array at the position of the first
+// occurrence of the field; otherwise each value of a repeated field is
+// written as a repeated member.
+func ToJSON(w io.Writer, r *stanza.Reader, arrays bool) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("[")
+	for i := 0; ; i++ {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "convert: ToJSON")
+		}
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		bw.WriteString("\n")
+		bw.Write(encodeRecord(rec, arrays))
+	}
+	bw.WriteString("\n]\n")
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "convert: ToJSON")
+	}
+	return nil
+}
+
+// ToNDJSON reads all the records from r and writes them into w as
+// newline-delimited JSON objects. Arrays is used as in ToJSON.
+func ToNDJSON(w io.Writer, r *stanza.Reader, arrays bool) error {
+	bw := bufio.NewWriter(w)
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "convert: ToNDJSON")
+		}
+		bw.Write(encodeRecord(rec, arrays))
+		bw.WriteString("\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "convert: ToNDJSON")
+	}
+	return nil
+}
+
+// encodeRecord returns a record encoded as a JSON object.
+func encodeRecord(rec stanza.Record, arrays bool) []byte {
+	b := &bytes.Buffer{}
+	b.WriteByte('{')
+	if !arrays {
+		for i, f := range rec {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeString(b, f.Name)
+			b.WriteByte(':')
+			writeString(b, f.Value)
+		}
+		b.WriteByte('}')
+		return b.Bytes()
+	}
+
+	for i, n := range rec.Names() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeString(b, n)
+		b.WriteByte(':')
+		vs := rec.Values(n)
+		if len(vs) == 1 {
+			writeString(b, vs[0])
+			continue
+		}
+		b.WriteByte('[')
+		for j, v := range vs {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			writeString(b, v)
+		}
+		b.WriteByte(']')
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// writeString writes a JSON string.
+func writeString(b *bytes.Buffer, s string) {
+	e := json.NewEncoder(b)
+	e.SetEscapeHTML(false)
+	e.Encode(s)
+	b.Truncate(b.Len() - 1) // remove the new line added by Encode
+}
+
+// FromJSON reads JSON objects from r, and writes them into w as stanza
+// records. The input can be either a JSON array of objects, or a stream of
+// objects (e.g. newline-delimited JSON). Each member of an object is a
+// field, and the fields are written in the order of the members (unless w
+// has a field list). Numbers and booleans are written as text, null values
+// are ignored, and the elements of an array are written as repeated
+// fields. Field names are stored in lower case, with spaces replaced by
+// '-'.
+func FromJSON(w *stanza.Writer, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "convert: FromJSON")
+		}
+		switch tok {
+		case json.Delim('['):
+			for dec.More() {
+				if err := expectDelim(dec, '{'); err != nil {
+					return errors.Wrap(err, "convert: FromJSON")
+				}
+				if err := decodeRecord(w, dec); err != nil {
+					return errors.Wrap(err, "convert: FromJSON")
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return errors.Wrap(err, "convert: FromJSON")
+			}
+		case json.Delim('{'):
+			if err := decodeRecord(w, dec); err != nil {
+				return errors.Wrap(err, "convert: FromJSON")
+			}
+		default:
+			return errors.Errorf("convert: FromJSON: offset %d: expecting an object or an array", dec.InputOffset())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "convert: FromJSON")
+	}
+	return nil
+}
+
+// expectDelim reads a delimiter from the decoder.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return errors.Errorf("offset %d: expecting %q", dec.InputOffset(), delim)
+	}
+	return nil
+}
+
+// decodeRecord decodes a JSON object, and writes it as a record. The
+// opening brace of the object should be already read.
+func decodeRecord(w *stanza.Writer, dec *json.Decoder) error {
+	var rec stanza.Record
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return errors.Errorf("offset %d: expecting a member name", dec.InputOffset())
+		}
+		name := fieldName(key)
+
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == json.Delim('[') {
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				v, ok, err := scalar(tok)
+				if err != nil {
+					return errors.Wrapf(err, "offset %d: member %q", dec.InputOffset(), key)
+				}
+				if ok && len(name) > 0 {
+					rec = append(rec, stanza.Field{Name: name, Value: v})
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+			continue
+		}
+		v, ok, err := scalar(tok)
+		if err != nil {
+			return errors.Wrapf(err, "offset %d: member %q", dec.InputOffset(), key)
+		}
+		if ok && len(name) > 0 {
+			rec = append(rec, stanza.Field{Name: name, Value: v})
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+	return w.WriteRecord(rec)
+}
+
+// scalar returns the text of a scalar JSON value. Ok is false for null
+// values.
+func scalar(tok json.Token) (v string, ok bool, err error) {
+	switch t := tok.(type) {
+	case string:
+		return t, true, nil
+	case json.Number:
+		return t.String(), true, nil
+	case bool:
+		return strconv.FormatBool(t), true, nil
+	case nil:
+		return "", false, nil
+	}
+	return "", false, errors.New("nested objects and arrays are not supported")
+}
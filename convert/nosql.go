@@ -0,0 +1,167 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+// ListToTable reads all the records from r and writes them into w as a
+// NoSQL table: a tab separated table in which the first line is a header
+// with the field names, the second line is a ruler of dashes under each
+// field name, and each of the following lines is a record. Tabs, new lines
+// and backslashes in the content of a field are escaped as "\t", "\n" and
+// "\\". If fields is empty, the fields returned by r.Fields are used as the
+// columns of the table, and all the records are read before any row is
+// written.
+//
+// It is equivalent to the NoSQL 'listtotable' operator.
+func ListToTable(w io.Writer, r *stanza.Reader, fields []string) error {
+	next, fields, err := source(r, fields)
+	if err != nil {
+		return errors.Wrap(err, "convert: ListToTable")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(strings.Join(fields, "\t") + "\n")
+	ruler := make([]string, len(fields))
+	for i, f := range fields {
+		ruler[i] = strings.Repeat("-", utf8.RuneCountInString(f))
+	}
+	bw.WriteString(strings.Join(ruler, "\t") + "\n")
+
+	row := make([]string, len(fields))
+	for {
+		rec, err := next()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "convert: ListToTable")
+		}
+		for i, f := range fields {
+			row[i] = tableEscaper.Replace(rec[f])
+		}
+		bw.WriteString(strings.Join(row, "\t") + "\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "convert: ListToTable")
+	}
+	return nil
+}
+
+// TableToList reads a NoSQL table from r and writes its rows into w as
+// stanza records. If w does not have a field list, the columns of the table
+// will be used as the field list of w.
+//
+// It is equivalent to the NoSQL 'tabletolist' operator.
+func TableToList(w *stanza.Writer, r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<24)
+	line := 0
+	next := func() (string, bool) {
+		if !s.Scan() {
+			return "", false
+		}
+		line++
+		return strings.TrimRight(s.Text(), "\r"), true
+	}
+
+	head, ok := next()
+	if !ok {
+		return errors.Wrap(s.Err(), "convert: TableToList")
+	}
+	cols := strings.Split(head, "\t")
+	for i, c := range cols {
+		cols[i] = strings.TrimLeft(c, "\x01") // old NoSQL header mark
+	}
+	fields, err := header(cols)
+	if err != nil {
+		return errors.Wrap(err, "convert: TableToList")
+	}
+	ruler, ok := next()
+	if !ok || strings.Trim(ruler, "-\t") != "" {
+		return errors.Errorf("convert: TableToList: line %d: expecting a ruler", line)
+	}
+	if len(w.Fields()) == 0 {
+		var fl []string
+		for _, f := range fields {
+			if len(f) > 0 {
+				fl = append(fl, f)
+			}
+		}
+		w.SetFields(fl)
+	}
+
+	for {
+		ln, ok := next()
+		if !ok {
+			break
+		}
+		row := strings.Split(ln, "\t")
+		if len(row) != len(fields) {
+			return errors.Errorf("convert: TableToList: line %d: found %d columns, want %d", line, len(row), len(fields))
+		}
+		rec := make(map[string]string, len(fields))
+		for i, v := range row {
+			if len(fields[i]) == 0 {
+				continue
+			}
+			rec[fields[i]] = tableUnescape(v)
+		}
+		if err := w.Write(rec); err != nil {
+			return errors.Wrap(err, "convert: TableToList")
+		}
+	}
+	if err := s.Err(); err != nil {
+		return errors.Wrap(err, "convert: TableToList")
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "convert: TableToList")
+	}
+	return nil
+}
+
+// tableEscaper escapes the content of a table cell.
+var tableEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\r", "", "\n", "\\n")
+
+// tableUnescape unescapes the content of a table cell.
+func tableUnescape(v string) string {
+	if !strings.Contains(v, "\\") {
+		return v
+	}
+	b := &bytes.Buffer{}
+	esc := false
+	for _, r1 := range v {
+		if !esc {
+			if r1 == '\\' {
+				esc = true
+				continue
+			}
+			b.WriteRune(r1)
+			continue
+		}
+		esc = false
+		switch r1 {
+		case 't':
+			b.WriteRune('\t')
+		case 'n':
+			b.WriteRune('\n')
+		default:
+			b.WriteRune(r1)
+		}
+	}
+	return b.String()
+}
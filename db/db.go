@@ -0,0 +1,299 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package db uses a stanza file as a small database.
+//
+// A Collection is loaded in memory, and its records are identified by the
+// content of a key field. After the collection is modified, it can be saved
+// back to the file, keeping the order of the records and fields.
+package db
+
+import (
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+// Errors returned by collection operations.
+var (
+	ErrNoKey     = errors.New("record without key")
+	ErrDuplicate = errors.New("duplicated key")
+	ErrNotFound  = errors.New("key not found")
+)
+
+// A Collection is a set of records stored in a stanza file, identified by
+// the content of a key field.
+type Collection struct {
+	Name    string             // file name
+	Key     string             // key field
+	Options stanza.FileOptions // options used to read and save the file
+
+	fields []string
+	spell  map[string]string
+	recs   []stanza.Record
+	index  map[string]int
+}
+
+// Load reads a collection from the named file, using key as the key field.
+func Load(name, key string) (*Collection, error) {
+	c := &Collection{Name: name, Key: key}
+	if err := c.Load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load reads the records of the collection from its file, replacing any
+// record in memory. If the file does not exist, the collection will be
+// empty. Each record of the file must have a unique key.
+func (c *Collection) Load() error {
+	c.fields = nil
+	c.spell = nil
+	c.recs = nil
+	c.index = make(map[string]int)
+
+	f, err := stanza.OpenFile(c.Name)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil
+		}
+		return errors.Wrap(err, "db: Load")
+	}
+	defer f.Close()
+	f.Dialect = c.Options.Dialect
+	for i := 1; ; i++ {
+		rec, err := f.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "db: Load: %s", c.Name)
+		}
+		if err := c.add(rec); err != nil {
+			return errors.Wrapf(err, "db: Load: %s: record %d", c.Name, i)
+		}
+	}
+	c.fields = append(c.fields, f.Fields()...)
+	c.spell = f.Spellings()
+	return nil
+}
+
+// Save writes the collection to its file. The file is replaced only if
+// all the records were written successfully.
+func (c *Collection) Save() error {
+	err := c.Options.UpdateFile(c.Name, func(in *stanza.Reader, out *stanza.Writer) error {
+		out.Dialect = c.Options.Dialect
+		if err := out.SetFields(c.fields); err != nil {
+			return err
+		}
+		out.SetSpelling(c.spell)
+		for _, rec := range c.recs {
+			if err := out.WriteRecord(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db: Save")
+	}
+	return nil
+}
+
+// Len returns the number of records in the collection.
+func (c *Collection) Len() int {
+	return len(c.recs)
+}
+
+// Keys returns the keys of the records, in the collection order.
+func (c *Collection) Keys() []string {
+	keys := make([]string, 0, len(c.recs))
+	for _, rec := range c.recs {
+		k, _ := rec.Get(c.Key)
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Fields returns the fields of the collection, in the order in which they
+// will be written. The caller should not modify this slice.
+func (c *Collection) Fields() []string {
+	return c.fields
+}
+
+// Get returns the record with the given key. The returned record is owned
+// by the caller.
+func (c *Collection) Get(key string) (stanza.Record, bool) {
+	i, ok := c.index[key]
+	if !ok {
+		return nil, false
+	}
+	return clone(c.recs[i]), true
+}
+
+// Insert adds a new record at the end of the collection.
+func (c *Collection) Insert(rec stanza.Record) error {
+	if err := c.add(clone(rec)); err != nil {
+		return errors.Wrap(err, "db: Insert")
+	}
+	c.addFields(rec)
+	return nil
+}
+
+// Update replaces the record with the same key as rec.
+func (c *Collection) Update(rec stanza.Record) error {
+	k, _ := rec.Get(c.Key)
+	if len(k) == 0 {
+		return errors.Wrap(ErrNoKey, "db: Update")
+	}
+	i, ok := c.index[k]
+	if !ok {
+		return errors.Wrapf(ErrNotFound, "db: Update: %q", k)
+	}
+	c.recs[i] = clone(rec)
+	c.addFields(rec)
+	return nil
+}
+
+// Delete removes the record with the given key.
+func (c *Collection) Delete(key string) error {
+	i, ok := c.index[key]
+	if !ok {
+		return errors.Wrapf(ErrNotFound, "db: Delete: %q", key)
+	}
+	c.recs = append(c.recs[:i], c.recs[i+1:]...)
+	delete(c.index, key)
+	for j := i; j < len(c.recs); j++ {
+		k, _ := c.recs[j].Get(c.Key)
+		c.index[k] = j
+	}
+	return nil
+}
+
+// Query returns the records for which p is true, in the collection order.
+// The returned records are owned by the caller.
+func (c *Collection) Query(p Predicate) []stanza.Record {
+	var recs []stanza.Record
+	for _, rec := range c.recs {
+		if p(rec) {
+			recs = append(recs, clone(rec))
+		}
+	}
+	return recs
+}
+
+// add adds a record to the collection.
+func (c *Collection) add(rec stanza.Record) error {
+	if c.index == nil {
+		c.index = make(map[string]int)
+	}
+	k, _ := rec.Get(c.Key)
+	if len(k) == 0 {
+		return ErrNoKey
+	}
+	if _, dup := c.index[k]; dup {
+		return errors.Wrapf(ErrDuplicate, "%q", k)
+	}
+	c.index[k] = len(c.recs)
+	c.recs = append(c.recs, rec)
+	return nil
+}
+
+// addFields adds the new fields of a record to the field list.
+func (c *Collection) addFields(rec stanza.Record) {
+	for _, n := range rec.Names() {
+		found := false
+		for _, f := range c.fields {
+			if f == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.fields = append(c.fields, n)
+		}
+	}
+}
+
+// clone returns a copy of a record.
+func clone(rec stanza.Record) stanza.Record {
+	nr := make(stanza.Record, len(rec))
+	copy(nr, rec)
+	return nr
+}
+
+// A Predicate reports whether a record matches a condition.
+type Predicate func(rec stanza.Record) bool
+
+// Equal returns a Predicate that is true if any value of the field is
+// equal to value.
+func Equal(field, value string) Predicate {
+	return func(rec stanza.Record) bool {
+		for _, v := range rec.Values(field) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Has returns a Predicate that is true if the field has a non-empty value.
+func Has(field string) Predicate {
+	return func(rec stanza.Record) bool {
+		for _, v := range rec.Values(field) {
+			if len(v) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Match returns a Predicate that is true if any value of the field matches
+// the regular expression.
+func Match(field string, re *regexp.Regexp) Predicate {
+	return func(rec stanza.Record) bool {
+		for _, v := range rec.Values(field) {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// And returns a Predicate that is true if all the predicates are true.
+func And(ps ...Predicate) Predicate {
+	return func(rec stanza.Record) bool {
+		for _, p := range ps {
+			if !p(rec) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a Predicate that is true if any of the predicates is true.
+func Or(ps ...Predicate) Predicate {
+	return func(rec stanza.Record) bool {
+		for _, p := range ps {
+			if p(rec) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a Predicate that is true if p is false.
+func Not(p Predicate) Predicate {
+	return func(rec stanza.Record) bool {
+		return !p(rec)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var blob = `# Countries
+ISO3166:	AR
+Name:	Argentina
+Capital: Buenos Aires
+%%
+ISO3166:	CL
+Name:	Chile
+Capital: Santiago
+%%
+ISO3166:	UY
+Name:	Uruguay
+Capital: Montevideo
+%%
+`
+
+func TestCollection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("collection: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "countries.stz")
+	if err := ioutil.WriteFile(name, []byte(blob), 0644); err != nil {
+		t.Fatalf("collection: %v", err)
+	}
+
+	c, err := Load(name, "iso3166")
+	if err != nil {
+		t.Fatalf("collection: %v", err)
+	}
+	if c.Len() != 3 {
+		t.Errorf("collection: found %d records, want %d", c.Len(), 3)
+	}
+	rec, ok := c.Get("CL")
+	if v, _ := rec.Get("capital"); !ok || v != "Santiago" {
+		t.Errorf("collection: get: found %v", rec)
+	}
+	rec = stanza.Record{{Name: "iso3166", Value: "CL"}, {Name: "name", Value: "Chile"}}
+	if err := c.Insert(rec); errors.Cause(err) != ErrDuplicate {
+		t.Errorf("collection: insert: error %v, want %v", err, ErrDuplicate)
+	}
+	rec = append(rec, stanza.Field{Name: "capital", Value: "Santiago de Chile"})
+	if err := c.Update(rec); err != nil {
+		t.Errorf("collection: update: %v", err)
+	}
+	if err := c.Insert(stanza.Record{{Name: "iso3166", Value: "PY"}, {Name: "name", Value: "Paraguay"}, {Name: "anthem", Value: "Paraguayos, República o Muerte"}}); err != nil {
+		t.Errorf("collection: insert: %v", err)
+	}
+	if err := c.Delete("UY"); err != nil {
+		t.Errorf("collection: delete: %v", err)
+	}
+	if err := c.Delete("UY"); errors.Cause(err) != ErrNotFound {
+		t.Errorf("collection: delete: error %v, want %v", err, ErrNotFound)
+	}
+	recs := c.Query(Or(Match("capital", regexp.MustCompile("^Santiago")), Not(Has("capital"))))
+	if len(recs) != 2 {
+		t.Errorf("collection: query: found %d records, want %d", len(recs), 2)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("collection: save: %v", err)
+	}
+
+	b, _ := ioutil.ReadFile(name)
+	want := "ISO3166: AR\r\nName:\tArgentina\r\nCapital: Buenos Aires\r\n%%\r\n" +
+		"ISO3166: CL\r\nName:\tChile\r\nCapital: Santiago de Chile\r\n%%\r\n" +
+		"ISO3166: PY\r\nName:\tParaguay\r\nanthem: Paraguayos, República o Muerte\r\n%%\r\n"
+	if string(b) != want {
+		t.Errorf("collection: saved %q, want %q", b, want)
+	}
+}
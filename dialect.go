@@ -0,0 +1,92 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import "strings"
+
+// A Dialect defines a variant of the stanza format. The zero value is the
+// stanza format described in the package documentation.
+type Dialect struct {
+	// Separator is the character that separates a field name from its
+	// content. If zero, ':' is used.
+	Separator rune
+
+	// Comments are the prefixes of comment lines. If nil, '#' is used.
+	Comments []string
+
+	// Terminator is the character that starts an end-of-record line. If
+	// zero, '%' is used.
+	Terminator rune
+
+	// If BlankSeparator is true, records are separated by one or more
+	// blank lines, and fields are written without alignment. Unless a
+	// Terminator is explicitly defined, no end-of-record mark is used.
+	BlankSeparator bool
+
+	// If NoComments is true, comment lines are not recognized.
+	NoComments bool
+
+	// If DotBlank is true, a blank line inside a multi-line field is
+	// encoded as a continuation line with a single '.' character.
+	DotBlank bool
+
+	// If KeepCase is true, field names are not folded to lower case.
+	KeepCase bool
+
+	// If KeepSpaces is true, spaces inside a field name are not replaced
+	// by '-'.
+	KeepSpaces bool
+}
+
+// Predefined dialects.
+var (
+	// Debian is the dialect of Debian control files (e.g. dpkg status
+	// files).
+	Debian = Dialect{BlankSeparator: true, DotBlank: true}
+
+	// RFC822 is the dialect of RFC 822 headers, in which records are
+	// separated by blank lines.
+	RFC822 = Dialect{BlankSeparator: true, NoComments: true}
+)
+
+// separator returns the field separator.
+func (d Dialect) separator() rune {
+	if d.Separator == 0 {
+		return ':'
+	}
+	return d.Separator
+}
+
+// terminator returns the end-of-record mark.
+func (d Dialect) terminator() rune {
+	if d.Terminator == 0 {
+		return '%'
+	}
+	return d.Terminator
+}
+
+// comments returns the comment prefixes.
+func (d Dialect) comments() []string {
+	if d.NoComments {
+		return nil
+	}
+	if d.Comments == nil {
+		return []string{"#"}
+	}
+	return d.Comments
+}
+
+// fieldName returns a field name in the canonical form of the dialect.
+func (d Dialect) fieldName(name string) string {
+	sep := "-"
+	if d.KeepSpaces {
+		sep = " "
+	}
+	name = strings.Join(strings.Fields(name), sep)
+	if !d.KeepCase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
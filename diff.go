@@ -0,0 +1,228 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A ChangeKind is the kind of change of a record.
+type ChangeKind int
+
+// Valid change kinds.
+const (
+	Modified ChangeKind = iota
+	Added
+	Removed
+)
+
+var changeNames = []string{"modified", "added", "removed"}
+
+func (k ChangeKind) String() string {
+	if int(k) < 0 || int(k) >= len(changeNames) {
+		return "unknown"
+	}
+	return changeNames[k]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// A FieldChange is a change in the values of a field. If the field is
+// repeated, all its values are stored.
+type FieldChange struct {
+	Field string   `json:"field"`
+	Old   []string `json:"old,omitempty"`
+	New   []string `json:"new,omitempty"`
+}
+
+// A Change is a difference between two versions of a record. In added and
+// removed records, all the fields of the record are stored as field
+// changes.
+type Change struct {
+	Kind   ChangeKind    `json:"change"`
+	Key    string        `json:"key"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// Diff reads all the records from the old (from) and new (to) readers, and
+// returns the differences between them. Records are matched by the content of the key
+// field, so the order of the records and the fields is ignored. Each key
+// must be unique, and records without key are ignored. Changes of the old
+// records are returned in the order of the old records, followed by the
+// added records.
+func Diff(from, to RecordReader, key string) ([]Change, error) {
+	orecs, okeys, err := keyRecords(from, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: Diff: old records")
+	}
+	nrecs, nkeys, err := keyRecords(to, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: Diff: new records")
+	}
+
+	var changes []Change
+	for _, k := range okeys {
+		o := orecs[k]
+		n, ok := nrecs[k]
+		if !ok {
+			changes = append(changes, Change{Kind: Removed, Key: k, Fields: fieldChanges(o, nil)})
+			continue
+		}
+		if fs := fieldChanges(o, n); len(fs) > 0 {
+			changes = append(changes, Change{Kind: Modified, Key: k, Fields: fs})
+		}
+	}
+	for _, k := range nkeys {
+		if _, ok := orecs[k]; ok {
+			continue
+		}
+		changes = append(changes, Change{Kind: Added, Key: k, Fields: fieldChanges(nil, nrecs[k])})
+	}
+	return changes, nil
+}
+
+// keyRecords reads the records of r, and returns them by key, as well as
+// the keys in the reading order.
+func keyRecords(r RecordReader, key string) (map[string]Record, []string, error) {
+	recs := make(map[string]Record)
+	var keys []string
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			return recs, keys, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		k, _ := rec.Get(key)
+		if len(k) == 0 {
+			continue
+		}
+		if _, dup := recs[k]; dup {
+			return nil, nil, errors.Errorf("duplicated key %q", k)
+		}
+		recs[k] = rec
+		keys = append(keys, k)
+	}
+}
+
+// fieldChanges returns the changed fields between an old and a new record.
+// Any of them can be nil.
+func fieldChanges(o, n Record) []FieldChange {
+	names := o.Names()
+	for _, f := range n.Names() {
+		if _, ok := o.Get(f); !ok {
+			names = append(names, f)
+		}
+	}
+	var fs []FieldChange
+	for _, f := range names {
+		ov, nv := o.Values(f), n.Values(f)
+		if equalValues(ov, nv) {
+			continue
+		}
+		fs = append(fs, FieldChange{Field: f, Old: ov, New: nv})
+	}
+	return fs
+}
+
+// equalValues returns true if two lists of values are equal.
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffFields are the fields of the records written by WriteDiff.
+var diffFields = []string{"change", "key", "field", "old", "new"}
+
+// WriteDiff writes the changes into w as stanza records. Each field change
+// is written as a record with the fields "change", "key", "field", "old"
+// and "new". If a field is repeated, the "old" and "new" fields are
+// repeated.
+func WriteDiff(w *Writer, changes []Change) error {
+	if err := w.SetFields(diffFields); err != nil {
+		return errors.Wrap(err, "stanza: WriteDiff")
+	}
+	for _, c := range changes {
+		for _, f := range c.Fields {
+			rec := Record{
+				{Name: "change", Value: c.Kind.String()},
+				{Name: "key", Value: c.Key},
+				{Name: "field", Value: f.Field},
+			}
+			for _, v := range f.Old {
+				rec = append(rec, Field{Name: "old", Value: v})
+			}
+			for _, v := range f.New {
+				rec = append(rec, Field{Name: "new", Value: v})
+			}
+			if err := w.WriteRecord(rec); err != nil {
+				return errors.Wrap(err, "stanza: WriteDiff")
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "stanza: WriteDiff")
+	}
+	return nil
+}
+
+// WriteUnified writes the changes into w as a human readable report,
+// similar to a unified diff. Each change starts with a header with the
+// key, followed by the old values of the changed fields, prefixed by '-',
+// and the new values, prefixed by '+'. The names of the compared files are
+// written in the first two lines.
+func WriteUnified(w io.Writer, changes []Change, oldName, newName string) error {
+	bw := bufio.NewWriter(w)
+	if len(changes) > 0 {
+		fmt.Fprintf(bw, "--- %s\n+++ %s\n", oldName, newName)
+	}
+	for _, c := range changes {
+		if c.Kind == Modified {
+			fmt.Fprintf(bw, "@@ %s @@\n", c.Key)
+		} else {
+			fmt.Fprintf(bw, "@@ %s (%s) @@\n", c.Key, c.Kind)
+		}
+		for _, f := range c.Fields {
+			for _, v := range f.Old {
+				writeUnifiedField(bw, '-', f.Field, v)
+			}
+			for _, v := range f.New {
+				writeUnifiedField(bw, '+', f.Field, v)
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "stanza: WriteUnified")
+	}
+	return nil
+}
+
+// writeUnifiedField writes a field of a unified report. Each line of a
+// multi-line value is prefixed with the mark.
+func writeUnifiedField(w *bufio.Writer, mark byte, field, value string) {
+	for i, ln := range strings.Split(value, "\n") {
+		if i == 0 {
+			fmt.Fprintf(w, "%c%s: %s\n", mark, field, ln)
+			continue
+		}
+		fmt.Fprintf(w, "%c\t%s\n", mark, ln)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	data := `Common: Chile
+ISO3166: CL
+Capital: Santiago
+%%
+Common: Uruguay
+ISO3166: UY
+Capital: Montevideo
+%%
+ISO3166: RU
+Capital: Moscow
+Population: 144192450
+Common: Russia
+Anthem: Славься, Отечество наше свободное,
+	Братских народов союз вековой,
+%%
+Common: South Korea
+ISO3166: KR
+Capital: Seoul
+Population: 51302044
+Anthem:	무궁화 삼천리 화려강산
+	대한 사람, 대한으로 길이 보전하세
+%%
+ISO3166: CN
+Common: China
+Name:	中华人民共和国
+Population: 1339724852
+Capital: Beijing
+%%
+`
+	changes, err := Diff(NewReader(strings.NewReader(blob)), NewReader(strings.NewReader(data)), "iso3166")
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := WriteUnified(out, changes, "a.stz", "b.stz"); err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	want := `--- a.stz
++++ b.stz
+@@ AR (removed) @@
+-name: República Argentina
+-common: Argentina
+-iso3166: AR
+-capital: Buenos Aires
+-population: 42669500
+-anthem: Ya su trono dignísimo abrieron
+-	las Provincias Unidas del Sud
+-	y los libres del mundo responden:
+-	"¡Al gran pueblo argentino, salud!"
+@@ KR @@
+-name: 대한민국
+@@ RU @@
+-name: Росси́я
+-anthem: Славься, Отечество наше свободное,
+-	Братских народов союз вековой,
+-	Предками данная мудрость народная!
+-	Славься, страна! Мы гордимся тобой!
++anthem: Славься, Отечество наше свободное,
++	Братских народов союз вековой,
+@@ CL (added) @@
++common: Chile
++iso3166: CL
++capital: Santiago
+@@ UY (added) @@
++common: Uruguay
++iso3166: UY
++capital: Montevideo
+`
+	if out.String() != want {
+		t.Errorf("diff: unified: found\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	w := NewWriter(out)
+	if err := WriteDiff(w, changes); err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	recs, err := readRecords(NewReader(out))
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(recs) != 15 {
+		t.Errorf("diff: found %d records, want %d", len(recs), 15)
+	}
+
+	b, err := json.Marshal(changes[1])
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if j := `{"change":"modified","key":"KR","fields":[{"field":"name","old":["대한민국"]}]}`; string(b) != j {
+		t.Errorf("diff: json: found %s, want %s", b, j)
+	}
+}
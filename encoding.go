@@ -0,0 +1,42 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bufio"
+	"io"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// NewEncodedReader returns a new Reader that reads from r, an input encoded
+// with the encoding e (e.g. charmap.ISO8859_1). If the input starts with a
+// byte order mark, the mark overrides the given encoding.
+func NewEncodedReader(r io.Reader, e encoding.Encoding) *Reader {
+	return NewReader(transform.NewReader(r, unicode.BOMOverride(e.NewDecoder())))
+}
+
+// NewEncodedWriter returns a new Writer that writes to w using the encoding
+// e. Writing a character that can not be represented in the encoding
+// results in an error.
+func NewEncodedWriter(w io.Writer, e encoding.Encoding) *Writer {
+	return NewWriter(transform.NewWriter(w, e.NewEncoder()))
+}
+
+// checkBOM checks the byte order mark at the start of the input. A UTF-8
+// mark is removed, and if an UTF-16 mark is found, the input is decoded as
+// UTF-16.
+func (r *Reader) checkBOM() {
+	b, _ := r.r.Peek(3)
+	switch {
+	case len(b) == 3 && b[0] == 0xef && b[1] == 0xbb && b[2] == 0xbf:
+		r.r.Discard(3)
+	case len(b) >= 2 && ((b[0] == 0xfe && b[1] == 0xff) || (b[0] == 0xff && b[1] == 0xfe)):
+		dec := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		r.r = bufio.NewReader(transform.NewReader(r.r, dec))
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestEncoding(t *testing.T) {
+	rec := map[string]string{"name": "República Argentina", "capital": "Buenos Aires"}
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+		bom  bool
+	}{
+		{"utf-8 with bom", unicode.UTF8, true},
+		{"utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), false},
+		{"utf-16be", unicode.UTF16(unicode.BigEndian, unicode.UseBOM), false},
+		{"latin-1", charmap.ISO8859_1, false},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		w := NewEncodedWriter(out, test.enc)
+		w.BOM = test.bom
+		w.SetFields([]string{"name", "capital"})
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("encoding: %s: %v", test.name, err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("encoding: %s: %v", test.name, err)
+		}
+
+		var r *Reader
+		if test.enc == charmap.ISO8859_1 {
+			if strings.Contains(out.String(), rec["name"]) {
+				t.Errorf("encoding: %s: output is UTF-8", test.name)
+			}
+			r = NewEncodedReader(out, test.enc)
+		} else {
+			r = NewReader(out)
+		}
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("encoding: %s: %v", test.name, err)
+		}
+		for f, v := range rec {
+			if got[f] != v {
+				t.Errorf("encoding: %s: field %q: found %q, want %q", test.name, f, got[f], v)
+			}
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	data := "Straße: Cafe\u0301\nName: Бии\u0306ск\n%%\n"
+	r := NewReader(strings.NewReader(data))
+	r.Normalize = NFC
+	r.FoldCase = true
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	want := map[string]string{"strasse": "Caf\u00e9", "name": "Би\u0439ск"}
+	for f, v := range want {
+		if rec[f] != v {
+			t.Errorf("normalize: field %q: found %q, want %q", f, rec[f], v)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.UseLF = true
+	w.Normalize = NFKC
+	w.Write(map[string]string{"name": "\ufb01n"})
+	w.Flush()
+	if want := "name:\tfin\n%%\n"; out.String() != want {
+		t.Errorf("normalize: written %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,522 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// An Expr is a compiled selection expression. An expression is built from
+// comparisons between field names and literal values, for example:
+//
+//	population > 50000000 && capital ~ "^B"
+//
+// A field name is a word made of letters, digits, and the characters '-',
+// '_' and '.'. Literal values are numbers, or strings enclosed in double
+// quotes (with Go escape sequences). The comparison operators are '==',
+// '!=', '<', '<=', '>' and '>=', and '~' and '!~' that match (or not
+// match) a regular expression, that must be a string literal. A field name
+// alone is true if the field has some content. Expressions are combined
+// with '&&', '||' and '!', and grouped with parentheses.
+//
+// If a field is repeated in a record, a comparison is true if it is true
+// for any of the field values. A missing field has an empty value.
+//
+// If Schema is defined and a compared field has a type other than Text,
+// the values are compared using the field type. Otherwise, if both values
+// are numbers, they are compared as numbers, and as strings in any other
+// case.
+type Expr struct {
+	Schema *Schema // schema used for typed comparisons
+
+	src  string
+	root node
+}
+
+// Compile parses a selection expression.
+func Compile(expr string) (*Expr, error) {
+	p := &parser{src: expr}
+	p.next()
+	n, err := p.parseOr()
+	if err == nil {
+		err = p.err
+	}
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %q", p.tok.text)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: Compile")
+	}
+	return &Expr{src: expr, root: n}, nil
+}
+
+// MustCompile is like Compile but panics if the expression can not be
+// parsed.
+func MustCompile(expr string) *Expr {
+	e, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source text of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Match returns true if the record matches the expression.
+func (e *Expr) Match(rec Record) bool {
+	return e.root.eval(rec.Values, e.Schema)
+}
+
+// MatchMap returns true if the record, as a map, matches the expression.
+func (e *Expr) MatchMap(rec map[string]string) bool {
+	return e.root.eval(mapValues(rec), e.Schema)
+}
+
+// mapValues returns a valuer for a record map.
+func mapValues(rec map[string]string) valuer {
+	return func(f string) []string {
+		if v, ok := rec[f]; ok {
+			return []string{v}
+		}
+		return nil
+	}
+}
+
+// A valuer returns the values of a field.
+type valuer func(field string) []string
+
+// A node is a node of an expression tree.
+type node interface {
+	eval(vals valuer, s *Schema) bool
+}
+
+type orNode struct{ l, r node }
+
+func (n *orNode) eval(vals valuer, s *Schema) bool {
+	return n.l.eval(vals, s) || n.r.eval(vals, s)
+}
+
+type andNode struct{ l, r node }
+
+func (n *andNode) eval(vals valuer, s *Schema) bool {
+	return n.l.eval(vals, s) && n.r.eval(vals, s)
+}
+
+type notNode struct{ n node }
+
+func (n *notNode) eval(vals valuer, s *Schema) bool {
+	return !n.n.eval(vals, s)
+}
+
+// hasNode is true if a field has some content.
+type hasNode struct{ field string }
+
+func (n *hasNode) eval(vals valuer, s *Schema) bool {
+	for _, v := range vals(n.field) {
+		if len(v) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// An operand is a field name or a literal value.
+type operand struct {
+	field bool
+	text  string
+}
+
+// values returns the values of an operand.
+func (o operand) values(vals valuer) []string {
+	if !o.field {
+		return []string{o.text}
+	}
+	vs := vals(o.text)
+	if len(vs) == 0 {
+		return []string{""}
+	}
+	return vs
+}
+
+// cmpNode is a comparison.
+type cmpNode struct {
+	op   string
+	l, r operand
+	re   *regexp.Regexp
+}
+
+func (n *cmpNode) eval(vals valuer, s *Schema) bool {
+	if n.re != nil {
+		match := false
+		for _, v := range n.l.values(vals) {
+			if n.re.MatchString(v) {
+				match = true
+				break
+			}
+		}
+		return match == (n.op == "~")
+	}
+	tp, typed := n.fieldType(s)
+	for _, a := range n.l.values(vals) {
+		for _, b := range n.r.values(vals) {
+			c := compare(a, b, tp, typed)
+			var ok bool
+			switch n.op {
+			case "==":
+				ok = c == 0
+			case "!=":
+				ok = c != 0
+			case "<":
+				ok = c < 0
+			case "<=":
+				ok = c <= 0
+			case ">":
+				ok = c > 0
+			case ">=":
+				ok = c >= 0
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldType returns the type of the compared fields, if it is defined in
+// the schema.
+func (n *cmpNode) fieldType(s *Schema) (Type, bool) {
+	if s == nil {
+		return Text, false
+	}
+	for _, o := range []operand{n.l, n.r} {
+		if !o.field {
+			continue
+		}
+		if f := s.Field(o.text); f != nil {
+			return f.Type, true
+		}
+	}
+	return Text, false
+}
+
+// compare compares two values. If typed is true, the values are compared
+// using the given type.
+func compare(a, b string, tp Type, typed bool) int {
+	if typed {
+		switch tp {
+		case Text:
+			return strings.Compare(a, b)
+		case Date:
+			ta, ea := parseDate(a)
+			tb, eb := parseDate(b)
+			if ea == nil && eb == nil {
+				switch {
+				case ta.Before(tb):
+					return -1
+				case ta.After(tb):
+					return 1
+				}
+				return 0
+			}
+		case Boolean:
+			ba, ea := parseBool(a)
+			bb, eb := parseBool(b)
+			if ea == nil && eb == nil {
+				switch {
+				case ba == bb:
+					return 0
+				case bb:
+					return -1
+				}
+				return 1
+			}
+		}
+	}
+	fa, ea := parseNumber(a)
+	fb, eb := parseNumber(b)
+	if ea == nil && eb == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// Token kinds.
+const (
+	tokEOF = iota
+	tokOp
+	tokWord
+	tokString
+)
+
+// A token is a lexical token of an expression.
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// A parser parses an expression.
+type parser struct {
+	src string
+	pos int
+	tok token
+	err error
+}
+
+// errorf returns an error at the position of the current token.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("column %d: "+format, append([]interface{}{p.tok.pos + 1}, args...)...)
+}
+
+// operators are the operators of an expression, longest first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!~", "<", ">", "~", "!", "(", ")"}
+
+// next reads the next token.
+func (p *parser) next() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	p.tok = token{pos: p.pos}
+	if p.pos >= len(p.src) {
+		p.tok.kind = tokEOF
+		return
+	}
+	s := p.src[p.pos:]
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			p.tok.kind, p.tok.text = tokOp, op
+			p.pos += len(op)
+			return
+		}
+	}
+	if s[0] == '"' {
+		p.tok.kind = tokString
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(s) {
+			p.tok.text = s
+			p.err = p.errorf("unterminated string")
+			p.pos = len(p.src)
+			return
+		}
+		v, err := strconv.Unquote(s[:i+1])
+		if err != nil {
+			p.err = p.errorf("invalid string %s", s[:i+1])
+		}
+		p.tok.text = v
+		p.pos += i + 1
+		return
+	}
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && strings.IndexRune("-_.+", r) < 0 {
+			break
+		}
+		i += size
+	}
+	if i == 0 {
+		r, _ := utf8.DecodeRuneInString(s)
+		p.tok.text = string(r)
+		p.err = p.errorf("unexpected %q", r)
+		p.pos = len(p.src)
+		return
+	}
+	p.tok.kind, p.tok.text = tokWord, s[:i]
+	p.pos += i
+}
+
+// parseOr parses an or expression.
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == "||" {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &orNode{l, r}
+	}
+	return l, nil
+}
+
+// parseAnd parses an and expression.
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == "&&" {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &andNode{l, r}
+	}
+	return l, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a
+// comparison.
+func (p *parser) parseUnary() (node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind == tokOp && p.tok.text == "!" {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n}, nil
+	}
+	if p.tok.kind == tokOp && p.tok.text == "(" {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokOp || p.tok.text != ")" {
+			return nil, p.errorf("expecting %q", ")")
+		}
+		p.next()
+		return n, nil
+	}
+	return p.parseCmp()
+}
+
+// parseCmp parses a comparison.
+func (p *parser) parseCmp() (node, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	var op string
+	switch p.tok.text {
+	case "==", "!=", "<", "<=", ">", ">=", "~", "!~":
+		if p.tok.kind == tokOp {
+			op = p.tok.text
+		}
+	}
+	if len(op) == 0 {
+		if !l.field {
+			return nil, p.errorf("expecting a comparison operator")
+		}
+		return &hasNode{l.text}, nil
+	}
+	p.next()
+	if op == "~" || op == "!~" {
+		if p.tok.kind != tokString {
+			return nil, p.errorf("expecting a regular expression")
+		}
+		re, err := regexp.Compile(p.tok.text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.next()
+		return &cmpNode{op: op, l: l, re: re}, nil
+	}
+	r, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &cmpNode{op: op, l: l, r: r}, nil
+}
+
+// parseOperand parses a field name or a literal value.
+func (p *parser) parseOperand() (operand, error) {
+	if p.err != nil {
+		return operand{}, p.err
+	}
+	var o operand
+	switch p.tok.kind {
+	case tokString:
+		o = operand{text: p.tok.text}
+	case tokWord:
+		if _, err := parseNumber(p.tok.text); err == nil {
+			o = operand{text: p.tok.text}
+		} else {
+			o = operand{field: true, text: p.tok.text}
+		}
+	case tokEOF:
+		return o, p.errorf("unexpected end of expression")
+	default:
+		return o, p.errorf("unexpected %q", p.tok.text)
+	}
+	p.next()
+	return o, nil
+}
+
+// A Filter is a reader that only returns the records that match an
+// expression. If the expression does not have a schema, and the filter
+// reads from a Reader, the schema of the Reader, if any, will be used.
+type Filter struct {
+	r RecordReader
+	e *Expr
+}
+
+// NewFilter returns a Filter that reads from r the records matching e.
+func NewFilter(r RecordReader, e *Expr) *Filter {
+	return &Filter{r: r, e: e}
+}
+
+// Read reads the next matching record as a map. If a field is repeated,
+// only the first value is stored in the map.
+func (f *Filter) Read() (map[string]string, error) {
+	rec, err := f.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+	return rec.Map(), nil
+}
+
+// ReadRecord reads the next matching record.
+func (f *Filter) ReadRecord() (Record, error) {
+	s := f.schema()
+	for {
+		rec, err := f.r.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if f.e.root.eval(rec.Values, s) {
+			return rec, nil
+		}
+	}
+}
+
+// schema returns the schema used by the filter.
+func (f *Filter) schema() *Schema {
+	if f.e.Schema != nil {
+		return f.e.Schema
+	}
+	if r, ok := f.r.(*Reader); ok {
+		return r.schema
+	}
+	return nil
+}
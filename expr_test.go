@@ -0,0 +1,81 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`population > 50000000 && capital ~ "^B"`, []string{"China"}},
+		{`population > 50000000 && !(capital ~ "^B")`, []string{"South Korea", "Russia"}},
+		{`iso3166 == "AR" || iso3166 == KR`, []string{"Argentina"}},
+		{`anthem`, []string{"Argentina", "South Korea", "Russia"}},
+		{`!anthem || common <= "Argentina"`, []string{"Argentina", "China"}},
+		{`population < 9e7 && capital !~ "^S"`, []string{"Argentina"}},
+		{`missing != ""`, nil},
+	}
+	for _, test := range tests {
+		e, err := Compile(test.expr)
+		if err != nil {
+			t.Errorf("expr: %q: %v", test.expr, err)
+			continue
+		}
+		f := NewFilter(NewReader(strings.NewReader(blob)), e)
+		var got []string
+		for {
+			rec, err := f.Read()
+			if errors.Cause(err) == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("expr: %q: %v", test.expr, err)
+			}
+			got = append(got, rec["common"])
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("expr: %q: found %v, want %v", test.expr, got, test.want)
+		}
+	}
+
+	// numeric comparison, unless the schema says otherwise
+	rec := Record{{Name: "code", Value: "10"}}
+	e := MustCompile("code > 9")
+	if !e.Match(rec) {
+		t.Errorf("expr: %q: numeric comparison failed", e)
+	}
+	e.Schema = &Schema{Fields: []*FieldSchema{{Name: "code", Type: Text}}}
+	if e.Match(rec) {
+		t.Errorf("expr: %q: text comparison failed", e)
+	}
+
+	// words that are not numbers are field names
+	rec = Record{{Name: "nan", Value: "x"}, {Name: "inf", Value: "1"}}
+	if e := MustCompile(`nan == "x" && inf < +2`); !e.Match(rec) {
+		t.Errorf("expr: %q: field names failed", e)
+	}
+
+	// special float values are compared as text
+	rec = Record{{Name: "x", Value: "NaN"}}
+	for src, want := range map[string]bool{"x == 1": false, "x != 1": true, `x == "NaN"`: true} {
+		if e := MustCompile(src); e.Match(rec) != want {
+			t.Errorf("expr: %q: match %v, want %v", e, !want, want)
+		}
+	}
+
+	for _, bad := range []string{"", "a ==", "(a == 1", `a ~ b`, "1", `a == "x`, "a = 1"} {
+		if _, err := Compile(bad); err == nil {
+			t.Errorf("expr: %q: expecting an error", bad)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Magic numbers of compressed files.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+
+	// magic numbers of the first bzip2 block, and of the end of an
+	// empty bzip2 stream
+	bzip2Block = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2End   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// isBzip2 returns true if the header of a file is the header of a bzip2
+// stream: "BZh", the block size ('1' to '9'), and a block magic number.
+func isBzip2(magic []byte) bool {
+	if len(magic) < 10 || !bytes.HasPrefix(magic, bzip2Magic) {
+		return false
+	}
+	if magic[3] < '1' || magic[3] > '9' {
+		return false
+	}
+	return bytes.Equal(magic[4:10], bzip2Block) || bytes.Equal(magic[4:10], bzip2End)
+}
+
+// A ReadCloser is a Reader that reads from a file.
+type ReadCloser struct {
+	*Reader
+	c closers
+}
+
+// Close closes the file.
+func (rc *ReadCloser) Close() error {
+	return rc.c.Close()
+}
+
+// closers is a list of io.Closer that are closed in reverse order.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var err error
+	for i := len(cs) - 1; i >= 0; i-- {
+		if e := cs[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// OpenFile opens the named file for reading. If the file is compressed with
+// gzip or bzip2, the file will be decompressed while read. The compression
+// is detected from the content of the file, not from the file name.
+func OpenFile(name string) (*ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: OpenFile")
+	}
+	rc := &ReadCloser{c: closers{f}}
+	r, err := decompress(f, rc)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "stanza: OpenFile: %s", name)
+	}
+	rc.Reader = NewReader(r)
+	return rc, nil
+}
+
+// decompress returns a reader that decompresses r, if r is compressed. The
+// closers of the decompressor are added to rc.
+func decompress(r io.Reader, rc *ReadCloser) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(10)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		rc.c = append(rc.c, z)
+		return z, nil
+	case isBzip2(magic):
+		return bzip2.NewReader(br), nil
+	}
+	return br, nil
+}
+
+// CreateFile creates the named file for writing, truncating it if it
+// already exists. If the name ends with ".gz", the output will be
+// compressed with gzip. The file is closed when the Writer is closed.
+func CreateFile(name string) (*Writer, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: CreateFile")
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return NewWriter(f), nil
+	}
+	z := gzip.NewWriter(f)
+	w := NewWriter(z)
+	w.c = closers{f, z}
+	return w, nil
+}
+
+// FileOptions are the options used to modify a file.
+type FileOptions struct {
+	// Backup is the suffix added to the file name to keep a copy of the
+	// original file (e.g. "~" or ".bak"). If empty, no copy is kept.
+	Backup string
+
+	// If Repair is true, a partial record at the end of a file opened
+	// with OpenAppender is removed.
+	Repair bool
+
+	// Dialect is the variant of the format of the file opened with
+	// OpenAppender. UpdateFile does not use it, but the caller can use
+	// it to set the dialect of the Reader and Writer of an UpdateFunc
+	// (e.g. db.Collection uses it to load and save its file).
+	Dialect Dialect
+
+	// If Lock is true, an exclusive advisory lock of the file is
+	// obtained before the file is modified, so processes that use the
+	// same lock can safely modify the file. UpdateFile holds the lock
+	// while the file is updated, and an Appender while each record is
+	// written. Locks are only supported on Unix systems.
+	Lock bool
+
+	// Timeout is the maximum time to wait for a lock. If the lock can
+	// not be obtained, an ErrLocked error is returned. If zero, the lock
+	// is tried only once.
+	Timeout time.Duration
+}
+
+// An UpdateFunc reads records from in, and writes the updated records to
+// out.
+type UpdateFunc func(in *Reader, out *Writer) error
+
+// UpdateFile updates the named file using the default options.
+func UpdateFile(name string, fn UpdateFunc) error {
+	var opt FileOptions
+	return opt.UpdateFile(name, fn)
+}
+
+// UpdateFile updates the named file. The records of the file are read from
+// the in Reader of fn, and the records written to out will replace the
+// content of the file. The new content is written to a temporary file in
+// the same directory, that replaces the original file only if fn and the
+// writing were successful, so the file is never left half-written. If the
+// file does not exist, in will not have records, and the file will be
+// created.
+//
+// If Lock is true and the file does not exist, an empty file is created
+// to be locked.
+//
+// As with OpenFile and CreateFile, compressed files are decompressed while
+// read, and if the name ends with ".gz", the output will be compressed.
+func (opt *FileOptions) UpdateFile(name string, fn UpdateFunc) error {
+	if err := opt.update(name, fn); err != nil {
+		return errors.Wrapf(err, "stanza: UpdateFile: %s", name)
+	}
+	return nil
+}
+
+func (opt *FileOptions) update(name string, fn UpdateFunc) error {
+	if opt.Lock {
+		flag := os.O_RDWR | os.O_CREATE
+		lf, err := os.OpenFile(name, flag, 0666)
+		if err != nil {
+			return err
+		}
+		lf, err = opt.lockFile(name, lf, flag)
+		defer lf.Close() // closing the file releases the lock
+		if err != nil {
+			return err
+		}
+	}
+
+	mode := os.FileMode(0666)
+	in := &ReadCloser{Reader: NewReader(strings.NewReader(""))}
+	st, err := os.Stat(name)
+	exists := err == nil
+	if exists {
+		mode = st.Mode().Perm()
+		if in, err = OpenFile(name); err != nil {
+			return errors.Cause(err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	defer in.Close()
+
+	dir, base := filepath.Split(name)
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // it fails if the file was renamed
+
+	var out *Writer
+	if strings.HasSuffix(name, ".gz") {
+		out = NewWriter(gzip.NewWriter(f))
+	} else {
+		out = NewWriter(f)
+		out.c = nil
+	}
+	if err := fn(in.Reader, out); err != nil {
+		f.Close()
+		return err
+	}
+	err = out.Close()
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); e != nil && err == nil {
+		err = e
+	}
+	if err != nil {
+		return errors.Cause(err)
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return err
+	}
+	if len(opt.Backup) > 0 && exists {
+		if err := backup(name, name+opt.Backup); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// backup makes a copy of a file. The copy is a hard link if it is
+// possible.
+func backup(name, bak string) error {
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(name, bak) == nil {
+		return nil
+	}
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(bak)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir commits the entries of a directory to stable storage. On some
+// systems directories can not be synced, so errors are ignored.
+func syncDir(dir string) {
+	if len(dir) == 0 {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
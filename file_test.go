@@ -0,0 +1,191 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCompressedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("compressed file: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	recs, err := readAll(NewReader(strings.NewReader(blob)))
+	if err != nil {
+		t.Fatalf("compressed file: %v", err)
+	}
+	for _, name := range []string{"countries.stz", "countries.stz.gz"} {
+		name = filepath.Join(dir, name)
+		w, err := CreateFile(name)
+		if err != nil {
+			t.Fatalf("compressed file: %v", err)
+		}
+		for _, rec := range recs {
+			w.Write(rec)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("compressed file: %s: %v", name, err)
+		}
+
+		// check that the file is compressed
+		b, _ := ioutil.ReadFile(name)
+		if gz := strings.HasSuffix(name, ".gz"); gz != strings.HasPrefix(string(b), string(gzipMagic)) {
+			t.Errorf("compressed file: %s: compressed %v", name, !gz)
+		}
+
+		r, err := OpenFile(name)
+		if err != nil {
+			t.Fatalf("compressed file: %v", err)
+		}
+		nr, err := readAll(r.Reader)
+		r.Close()
+		if err != nil {
+			t.Fatalf("compressed file: %s: %v", name, err)
+		}
+		if len(nr) != len(recs) {
+			t.Errorf("compressed file: %s: found %d records, want %d", name, len(nr), len(recs))
+		}
+	}
+}
+
+func TestBzip2Detection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("bzip2 detection: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string]string{
+		// a plain file with a field that starts like a bzip2 header
+		"plain.stz": "BZhello: Argentina\n%%\n",
+		"countries.stz.bz2": "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\xb9\x23\x51\xed\x00\x00\x07\xdd\x80\x00" +
+			"\x10\x42\x00\x00\x10\x20\x00\x22\xa3\x14\x00\x20\x00\x31\x00\xd0\x01\x04\xc4\x3c" +
+			"\x9a\x5c\xd4\x4f\x6c\xb1\x18\x8a\x01\x0d\x17\x72\x45\x38\x50\x90\xb9\x23\x51\xed",
+	}
+	for name, data := range tests {
+		name = filepath.Join(dir, name)
+		ioutil.WriteFile(name, []byte(data), 0666)
+		r, err := OpenFile(name)
+		if err != nil {
+			t.Errorf("bzip2 detection: %v", err)
+			continue
+		}
+		recs, err := readAll(r.Reader)
+		r.Close()
+		if err != nil {
+			t.Errorf("bzip2 detection: %s: %v", name, err)
+			continue
+		}
+		if len(recs) != 1 || (recs[0]["bzhello"] != "Argentina" && recs[0]["name"] != "Argentina") {
+			t.Errorf("bzip2 detection: %s: found %v", name, recs)
+		}
+	}
+}
+
+func TestFlushCompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("flush compressed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "countries.stz.gz")
+	w, err := CreateFile(name)
+	if err != nil {
+		t.Fatalf("flush compressed: %v", err)
+	}
+	defer w.Close()
+	w.UseLF = true
+	w.WriteRecord(Record{{Name: "name", Value: "Argentina"}})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("flush compressed: %v", err)
+	}
+
+	// the file is still open, so the gzip stream is not complete
+	b, _ := ioutil.ReadFile(name)
+	z, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("flush compressed: %v", err)
+	}
+	want := "name:\tArgentina\n%%\n"
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(z, got); err != nil {
+		t.Fatalf("flush compressed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("flush compressed: found %q, want %q", got, want)
+	}
+}
+
+func TestUpdateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("update file: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "countries.stz")
+	opt := &FileOptions{Backup: "~"}
+	add := func(in *Reader, out *Writer) error {
+		recs, err := readAll(in)
+		if err != nil {
+			return err
+		}
+		recs = append(recs, map[string]string{"name": "Chile"})
+		for _, rec := range recs {
+			out.Write(rec)
+		}
+		return nil
+	}
+	for i := 1; i <= 2; i++ {
+		if err := opt.UpdateFile(name, add); err != nil {
+			t.Fatalf("update file: %v", err)
+		}
+		recs, _ := readFile(name)
+		if len(recs) != i {
+			t.Errorf("update file: found %d records, want %d", len(recs), i)
+		}
+	}
+	if recs, _ := readFile(name + "~"); len(recs) != 1 {
+		t.Errorf("update file: backup: found %d records, want %d", len(recs), 1)
+	}
+
+	fail := errors.New("failed update")
+	err = UpdateFile(name, func(in *Reader, out *Writer) error {
+		out.Write(map[string]string{"name": "Peru"})
+		return fail
+	})
+	if errors.Cause(err) != fail {
+		t.Errorf("update file: error %v, want %v", err, fail)
+	}
+	if recs, _ := readFile(name); len(recs) != 2 {
+		t.Errorf("update file: failed update: found %d records, want %d", len(recs), 2)
+	}
+	if fs, _ := ioutil.ReadDir(dir); len(fs) != 2 {
+		t.Errorf("update file: found %d files, want %d", len(fs), 2)
+	}
+}
+
+// readFile reads all the records from a file.
+func readFile(name string) ([]map[string]string, error) {
+	r, err := OpenFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAll(r.Reader)
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsorted is returned by a sorted Join if an input is not sorted by its
+// key.
+var ErrUnsorted = errors.New("input not sorted by key")
+
+// A JoinType is the kind of relational join.
+type JoinType int
+
+// Valid join types.
+const (
+	// Only the pairs of records with the same key are returned.
+	InnerJoin JoinType = iota
+
+	// As InnerJoin, and the left records without a matching right
+	// record are also returned.
+	LeftJoin
+
+	// As LeftJoin, and the right records without a matching left
+	// record are also returned.
+	FullJoin
+)
+
+// A Join is a reader that returns the relational join of the records of
+// two readers, matched by the content of a key field in each reader. Each
+// returned record has the fields of the left record, followed by the
+// fields of the right record, except its key. In records with only a right
+// record (in a FullJoin), the right key is renamed as the left key. If a
+// field is repeated in the key, only its first value is used.
+//
+// If both inputs have a field with the same name (other than the key), the
+// field of the left records is renamed with LeftPrefix, and the field of the
+// right records with RightPrefix, even in records with only a left or a
+// right record. A right field with the name of the left key is always
+// renamed with RightPrefix. In a sort-merge join, only the names of the
+// records already read are known.
+//
+// By default, all the right records are stored in memory, and the left
+// records are read one by one. If Sorted is true, both inputs must be
+// sorted by their key (as strings, in ascending order, e.g. using Sort),
+// and only the right records with the same key are stored in memory.
+//
+// The fields of the Join should be set before the first call to
+// ReadRecord.
+type Join struct {
+	Type        JoinType
+	LeftPrefix  string // by default, ""
+	RightPrefix string // by default, "right-"
+	Sorted      bool   // the inputs are sorted by key
+
+	left, right       RecordReader
+	leftKey, rightKey string
+	started           bool
+	pending           []Record
+	lnames, rnames    map[string]bool // names of the fields read
+
+	// hash join
+	recs    []Record
+	index   map[string][]int
+	matched []bool
+	done    bool
+
+	// sort-merge join
+	group    []Record // right records with the same key
+	gkey     string
+	gmatched bool
+	next     Record // next right record
+	lkey     string // last left key
+}
+
+// NewJoin returns a Join that reads from the left and right readers, and
+// matches the records using the given key fields. Records with an empty key
+// are never matched.
+func NewJoin(left, right RecordReader, leftKey, rightKey string) *Join {
+	return &Join{
+		RightPrefix: "right-",
+		left:        left,
+		right:       right,
+		leftKey:     leftKey,
+		rightKey:    rightKey,
+		lnames:      map[string]bool{leftKey: true},
+		rnames:      make(map[string]bool),
+	}
+}
+
+// Read reads the next joined record as a map. If a field is repeated, only
+// the first value is stored in the map.
+func (j *Join) Read() (map[string]string, error) {
+	rec, err := j.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+	return rec.Map(), nil
+}
+
+// ReadRecord reads the next joined record.
+func (j *Join) ReadRecord() (Record, error) {
+	for len(j.pending) == 0 {
+		var err error
+		if j.Sorted {
+			err = j.mergeNext()
+		} else {
+			err = j.hashNext()
+		}
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				return nil, err
+			}
+			return nil, errors.Wrap(err, "stanza: Join")
+		}
+	}
+	rec := j.pending[0]
+	j.pending = j.pending[1:]
+	return rec, nil
+}
+
+// hashNext adds the next joined records to the pending list, using a hash
+// join.
+func (j *Join) hashNext() error {
+	if !j.started {
+		j.started = true
+		j.index = make(map[string][]int)
+		for {
+			rec, err := j.right.ReadRecord()
+			if errors.Cause(err) == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			j.addNames(j.rnames, rec, j.rightKey)
+			if k, _ := rec.Get(j.rightKey); len(k) > 0 {
+				j.index[k] = append(j.index[k], len(j.recs))
+			}
+			j.recs = append(j.recs, rec)
+		}
+		j.matched = make([]bool, len(j.recs))
+	}
+	if j.done {
+		return io.EOF
+	}
+
+	rec, err := j.left.ReadRecord()
+	if errors.Cause(err) == io.EOF {
+		j.done = true
+		if j.Type == FullJoin {
+			for i, r := range j.recs {
+				if !j.matched[i] {
+					j.pending = append(j.pending, j.combine(nil, r))
+				}
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	j.addNames(j.lnames, rec, j.leftKey)
+	k, _ := rec.Get(j.leftKey)
+	var idx []int
+	if len(k) > 0 {
+		idx = j.index[k]
+	}
+	for _, i := range idx {
+		j.matched[i] = true
+		j.pending = append(j.pending, j.combine(rec, j.recs[i]))
+	}
+	if len(idx) == 0 && j.Type != InnerJoin {
+		j.pending = append(j.pending, j.combine(rec, nil))
+	}
+	return nil
+}
+
+// mergeNext adds the next joined records to the pending list, using a
+// sort-merge join.
+func (j *Join) mergeNext() error {
+	if !j.started {
+		j.started = true
+		var err error
+		if j.next, err = j.readRight(); err != nil {
+			return err
+		}
+		if err := j.nextGroup(); err != nil {
+			return err
+		}
+	}
+	if j.done {
+		return io.EOF
+	}
+
+	rec, err := j.left.ReadRecord()
+	if errors.Cause(err) == io.EOF {
+		j.done = true
+		for len(j.group) > 0 {
+			j.flushGroup()
+			if err := j.nextGroup(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	j.addNames(j.lnames, rec, j.leftKey)
+	k, _ := rec.Get(j.leftKey)
+	if k < j.lkey {
+		return ErrUnsorted
+	}
+	j.lkey = k
+	for len(j.group) > 0 && j.gkey < k {
+		j.flushGroup()
+		if err := j.nextGroup(); err != nil {
+			return err
+		}
+	}
+	if len(j.group) > 0 && j.gkey == k && len(k) > 0 {
+		j.gmatched = true
+		for _, r := range j.group {
+			j.pending = append(j.pending, j.combine(rec, r))
+		}
+		return nil
+	}
+	if j.Type != InnerJoin {
+		j.pending = append(j.pending, j.combine(rec, nil))
+	}
+	return nil
+}
+
+// readRight reads the next right record. It returns nil at the end of the
+// input.
+func (j *Join) readRight() (Record, error) {
+	rec, err := j.right.ReadRecord()
+	if errors.Cause(err) == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.addNames(j.rnames, rec, j.rightKey)
+	return rec, nil
+}
+
+// addNames adds the field names of a record, except the key, to a set of
+// names.
+func (j *Join) addNames(names map[string]bool, rec Record, key string) {
+	for _, f := range rec {
+		if f.Name != key {
+			names[f.Name] = true
+		}
+	}
+}
+
+// nextGroup reads the next group of right records with the same key.
+func (j *Join) nextGroup() error {
+	j.group, j.gmatched = nil, false
+	if j.next == nil {
+		return nil
+	}
+	k, _ := j.next.Get(j.rightKey)
+	if k < j.gkey {
+		return ErrUnsorted
+	}
+	j.gkey = k
+	for j.next != nil {
+		if nk, _ := j.next.Get(j.rightKey); nk != k {
+			break
+		}
+		j.group = append(j.group, j.next)
+		var err error
+		if j.next, err = j.readRight(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushGroup adds the current group of right records to the pending list,
+// if they were not matched in a FullJoin.
+func (j *Join) flushGroup() {
+	if j.gmatched || j.Type != FullJoin {
+		return
+	}
+	for _, r := range j.group {
+		j.pending = append(j.pending, j.combine(nil, r))
+	}
+}
+
+// combine returns the join of a left and a right record. Any of them can
+// be nil.
+func (j *Join) combine(left, right Record) Record {
+	rec := make(Record, 0, len(left)+len(right))
+	for _, f := range left {
+		if f.Name != j.leftKey && j.rnames[f.Name] && len(j.LeftPrefix) > 0 {
+			f.Name, f.Spelling = j.LeftPrefix+f.Name, ""
+		}
+		rec = append(rec, f)
+	}
+	for _, f := range right {
+		if f.Name == j.rightKey {
+			if left != nil {
+				continue
+			}
+			f.Name, f.Spelling = j.leftKey, ""
+			rec = append(rec, f)
+			continue
+		}
+		if j.lnames[f.Name] && len(j.RightPrefix) > 0 {
+			f.Name, f.Spelling = j.RightPrefix+f.Name, ""
+		}
+		rec = append(rec, f)
+	}
+	return rec
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"strings"
+	"testing"
+)
+
+var taxa = `id: 1
+name: Puma concolor
+%%
+id: 2
+name: Lama guanicoe
+%%
+id: 3
+name: Rhea americana
+%%
+`
+
+var distribution = `taxon: 1
+name: Argentina
+%%
+taxon: 1
+name: Chile
+%%
+taxon: 3
+name: Uruguay
+%%
+taxon: 4
+name: Peru
+%%
+`
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		tp   JoinType
+		want []string
+	}{
+		{InnerJoin, []string{
+			"id=1;name=Puma concolor;right-name=Argentina",
+			"id=1;name=Puma concolor;right-name=Chile",
+			"id=3;name=Rhea americana;right-name=Uruguay",
+		}},
+		{LeftJoin, []string{
+			"id=1;name=Puma concolor;right-name=Argentina",
+			"id=1;name=Puma concolor;right-name=Chile",
+			"id=2;name=Lama guanicoe",
+			"id=3;name=Rhea americana;right-name=Uruguay",
+		}},
+		{FullJoin, []string{
+			"id=1;name=Puma concolor;right-name=Argentina",
+			"id=1;name=Puma concolor;right-name=Chile",
+			"id=2;name=Lama guanicoe",
+			"id=3;name=Rhea americana;right-name=Uruguay",
+			"id=4;right-name=Peru",
+		}},
+	}
+	for _, test := range tests {
+		for _, sorted := range []bool{false, true} {
+			j := NewJoin(NewReader(strings.NewReader(taxa)), NewReader(strings.NewReader(distribution)), "id", "taxon")
+			j.Type = test.tp
+			j.Sorted = sorted
+			recs, err := readRecords(j)
+			if err != nil {
+				t.Fatalf("join: %v", err)
+			}
+			var got []string
+			for _, rec := range recs {
+				var fs []string
+				for _, f := range rec {
+					fs = append(fs, f.Name+"="+f.Value)
+				}
+				got = append(got, strings.Join(fs, ";"))
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("join: type %d: sorted %v: found\n%s\nwant\n%s", test.tp, sorted, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		}
+	}
+
+	// a right field with the name of the left key
+	specimens := "taxon: 1\nid: X9\n%%\ntaxon: 4\nid: Y2\n%%\n"
+	j := NewJoin(NewReader(strings.NewReader(taxa)), NewReader(strings.NewReader(specimens)), "id", "taxon")
+	j.Type = FullJoin
+	recs, err := readRecords(j)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	want := []string{
+		"id=1;name=Puma concolor;right-id=X9",
+		"id=2;name=Lama guanicoe",
+		"id=3;name=Rhea americana",
+		"id=4;right-id=Y2",
+	}
+	var got []string
+	for _, rec := range recs {
+		var fs []string
+		for _, f := range rec {
+			fs = append(fs, f.Name+"="+f.Value)
+		}
+		got = append(got, strings.Join(fs, ";"))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("join: key name: found\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	j = NewJoin(NewReader(strings.NewReader(distribution)), NewReader(strings.NewReader(taxa)), "name", "name")
+	j.Sorted = true
+	if _, err := readRecords(j); err == nil {
+		t.Errorf("join: expecting error %v", ErrUnsorted)
+	}
+}
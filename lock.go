@@ -0,0 +1,53 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLocked is returned when the lock of a file can not be obtained.
+var ErrLocked = errors.New("file is locked by another process")
+
+// lockDelay is the time between attempts to lock a file.
+const lockDelay = 10 * time.Millisecond
+
+// lockFile obtains an exclusive lock on the named file, opened as f with
+// the given flag. If the file was replaced while waiting for the lock (e.g.
+// by UpdateFile), the file is reopened, and the new file is returned.
+func (opt *FileOptions) lockFile(name string, f *os.File, flag int) (*os.File, error) {
+	deadline := time.Now().Add(opt.Timeout)
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			return f, err
+		}
+		if ok {
+			st, err := f.Stat()
+			if err != nil {
+				unlock(f)
+				return f, err
+			}
+			if nst, err := os.Stat(name); err == nil && os.SameFile(st, nst) {
+				return f, nil
+			}
+			unlock(f)
+			nf, err := os.OpenFile(name, flag, 0666)
+			if err != nil {
+				return f, err
+			}
+			f.Close()
+			f = nf
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return f, ErrLocked
+		}
+		time.Sleep(lockDelay)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package stanza
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// tryLock returns an error, as file locking is not supported.
+func tryLock(f *os.File) (bool, error) {
+	return false, errors.New("file locking not supported")
+}
+
+// unlock releases the lock on f.
+func unlock(f *os.File) error {
+	return nil
+}
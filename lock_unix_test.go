@@ -0,0 +1,71 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package stanza
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stanza")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "events.stz")
+	opt := &FileOptions{Lock: true, Timeout: 50 * time.Millisecond}
+	a, err := opt.OpenAppender(name)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	defer a.Close()
+
+	// lock the file from another process
+	f, _ := os.Open(name)
+	if ok, err := tryLock(f); !ok {
+		t.Fatalf("lock: file not locked: %v", err)
+	}
+	if err := a.Write(map[string]string{"event": "start"}); errors.Cause(err) != ErrLocked {
+		t.Errorf("lock: appender: error %v, want %v", err, ErrLocked)
+	}
+	err = opt.UpdateFile(name, func(in *Reader, out *Writer) error { return nil })
+	if errors.Cause(err) != ErrLocked {
+		t.Errorf("lock: update: error %v, want %v", err, ErrLocked)
+	}
+
+	// the lock is released while waiting
+	opt.Timeout = time.Second
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		f.Close()
+	}()
+	if err := opt.UpdateFile(name, func(in *Reader, out *Writer) error {
+		return out.Write(map[string]string{"event": "update"})
+	}); err != nil {
+		t.Fatalf("lock: update: %v", err)
+	}
+
+	// the file was replaced by the update
+	if err := a.Write(map[string]string{"event": "start"}); err != nil {
+		t.Errorf("lock: appender: %v", err)
+	}
+	recs, err := readFile(name)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Errorf("lock: found %d records, want %d", len(recs), 2)
+	}
+}
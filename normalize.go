@@ -0,0 +1,44 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// A Normalization is a Unicode normalization form applied to field names and
+// field contents.
+type Normalization int
+
+// Valid normalization forms.
+const (
+	// No normalization.
+	NoNorm Normalization = iota
+
+	// Canonical composition (e.g. "и" followed by a combining acute
+	// accent is stored as the precomposed "й").
+	NFC
+
+	// Compatibility composition (e.g. "ﬁ" is stored as "fi").
+	NFKC
+)
+
+// apply returns the normalized form of s.
+func (n Normalization) apply(s string) string {
+	switch n {
+	case NFC:
+		return norm.NFC.String(s)
+	case NFKC:
+		return norm.NFKC.String(s)
+	}
+	return s
+}
+
+// foldCase returns a string folded with the full Unicode case folding rules
+// (e.g. "Straße" is folded as "strasse").
+func foldCase(s string) string {
+	return cases.Fold().String(s)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+// A Projection is a reader that only returns some fields of the records
+// read from another reader, optionally renaming them. Records without any
+// field with content after the projection are skipped.
+type Projection struct {
+	r      RecordReader
+	fields []string
+	rename map[string]string
+}
+
+// NewProjection returns a Projection that reads from r. Fields are the
+// fields to be returned, in the given order; if empty, all the fields are
+// returned in the record order. Rename maps a field name to its new name;
+// it can be nil.
+func NewProjection(r RecordReader, fields []string, rename map[string]string) *Projection {
+	return &Projection{r: r, fields: fields, rename: rename}
+}
+
+// Fields returns the projected fields, with the new names. If the
+// projection keeps all the fields, it returns nil.
+func (p *Projection) Fields() []string {
+	if len(p.fields) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(p.fields))
+	for _, f := range p.fields {
+		fields = append(fields, p.name(f))
+	}
+	return fields
+}
+
+// Read reads the next projected record as a map. If a field is repeated,
+// only the first value is stored in the map.
+func (p *Projection) Read() (map[string]string, error) {
+	rec, err := p.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+	return rec.Map(), nil
+}
+
+// ReadRecord reads the next projected record.
+func (p *Projection) ReadRecord() (Record, error) {
+	for {
+		rec, err := p.r.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if rec = p.project(rec); len(rec) > 0 {
+			return rec, nil
+		}
+	}
+}
+
+// project returns the projection of a record.
+func (p *Projection) project(rec Record) Record {
+	var nr Record
+	add := func(f Field) {
+		if len(f.Value) == 0 {
+			return
+		}
+		if n := p.name(f.Name); n != f.Name {
+			f.Name, f.Spelling = n, ""
+		}
+		nr = append(nr, f)
+	}
+	if len(p.fields) == 0 {
+		for _, f := range rec {
+			add(f)
+		}
+		return nr
+	}
+	for _, name := range p.fields {
+		for _, f := range rec {
+			if f.Name == name {
+				add(f)
+			}
+		}
+	}
+	return nr
+}
+
+// name returns the new name of a field.
+func (p *Projection) name(f string) string {
+	if n, ok := p.rename[f]; ok {
+		return n
+	}
+	return f
+}
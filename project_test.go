@@ -0,0 +1,57 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestProjection(t *testing.T) {
+	r := NewReader(strings.NewReader(blob))
+	f := NewFilter(r, MustCompile("population > 50000000"))
+	p := NewProjection(f, []string{"iso3166", "common"}, map[string]string{"iso3166": "code"})
+	if fs := strings.Join(p.Fields(), ","); fs != "code,common" {
+		t.Errorf("projection: fields %q", fs)
+	}
+	recs, err := readRecords(p)
+	if err != nil {
+		t.Fatalf("projection: %v", err)
+	}
+	var got []string
+	for _, rec := range recs {
+		if names := strings.Join(rec.Names(), ","); names != "code,common" {
+			t.Errorf("projection: record fields %q", names)
+		}
+		got = append(got, rec[0].Value)
+	}
+	if strings.Join(got, ",") != "KR,CN,RU" {
+		t.Errorf("projection: found %v", got)
+	}
+
+	// records without content are skipped
+	p = NewProjection(NewReader(strings.NewReader(blob)), []string{"anthem"}, nil)
+	if recs, _ := readRecords(p); len(recs) != 3 {
+		t.Errorf("projection: found %d records, want %d", len(recs), 3)
+	}
+}
+
+// readRecords reads all the records from r.
+func readRecords(r RecordReader) ([]Record, error) {
+	var recs []Record
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+}
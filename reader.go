@@ -57,6 +57,10 @@
 //		Славься, страна! Мы гордимся тобой!
 //	%%
 //
+// Variants of the format (e.g. a different field separator, or records
+// separated by blank lines) can be read and written by setting the Dialect
+// of a Reader or a Writer.
+//
 // Stanza file format are inspired by the record-jar/stanza format described
 // by E. Raymond "The Art of UNIX programming" (2003) Addison-Wesley
 // (<http://www.catb.org/esr/writings/taoup/html/ch05s02.html#id2906931>), and
@@ -67,61 +71,237 @@ package stanza
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
-	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
 
 // A Reader reads records from a stanza-encoded file.
+//
+// Dialect defines the variant of the format to be read. It should be set
+// before the first call to Read.
+//
+// Normalize is the Unicode normalization applied to field names and field
+// contents (e.g. with NFC, "и" followed by a combining breve is read as
+// "й"). If FoldCase is true, field names are folded using full Unicode case
+// folding rules (e.g. "ß" is folded as "ss"), instead of being just
+// converted to lower case.
+//
+// If Strict is true, the reader also returns an error when a field name is
+// not in its canonical form (lower case, with spaces replaced by '-'), when
+// a field has content but no name, or when the last record of the file does
+// not have an end-of-record mark.
 type Reader struct {
+	Strict    bool          // check the canonical form of the file
+	Dialect   Dialect       // variant of the format
+	Check     CheckMode     // handling of invalid characters
+	Normalize Normalization // normalization of names and contents
+	FoldCase  bool          // fold the case of field names
+
 	line   int
-	fields []string        // sorted list of fields
-	fok    map[string]bool // list of present fields
+	col    int // column of the last rune read
+	pcol   int // column before the last rune read
+	cline  int // line of the last checked invalid rune
+	ccol   int // column of the last checked invalid rune
+	cerr   error
+	warns  []error
+	fline  int               // line of the last field name
+	canon  bool              // last field name is in canonical form
+	raw    string            // spelling of the last field name
+	spell  map[string]string // original spelling of the fields
+	term   bool              // an end-of-record mark was found
+	bom    bool              // the byte order mark was checked
+	fields []string          // sorted list of fields
+	fok    map[string]bool   // list of present fields
+	schema *Schema
 	r      *bufio.Reader
 	b      *bytes.Buffer
 }
 
-// NewReader returns a new Reader that reads from r.
+// Errors returned by the reader as the Err of a ParseError.
+var (
+	ErrNoName       = errors.New("field without name")
+	ErrNoTerminator = errors.New("record without end-of-record mark")
+	ErrInvalidUTF8  = errors.New("invalid UTF-8 encoding")
+	ErrControl      = errors.New("invalid control character")
+)
+
+// A ParseError is returned for parsing errors. Line and column numbers are
+// 1-indexed. Column is the position of the character (not the byte) in the
+// line, it is 0 if the error is not about a particular character.
+type ParseError struct {
+	Line   int   // line where the error occurred
+	Column int   // column where the error occurred
+	Err    error // the actual error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line: %d: column: %d: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("line: %d: %v", e.Line, e.Err)
+}
+
+// A CheckMode defines how a Reader handles invalid characters: invalid UTF-8
+// encoded characters, and control characters other than tab, carriage
+// return and new line.
+type CheckMode int
+
+// Valid check modes.
+const (
+	// Invalid characters are silently read (invalid UTF-8 characters
+	// are read as U+FFFD).
+	Ignore CheckMode = iota
+
+	// Invalid characters are reported as warnings.
+	Warn
+
+	// Invalid characters are reported as errors.
+	Fail
+)
+
+// NewReader returns a new Reader that reads from r. The input should be
+// encoded as UTF-8. If the input starts with a byte order mark, the mark is
+// removed, and if it is a UTF-16 mark, the input will be decoded as UTF-16.
+// To read files with other encodings use NewEncodedReader.
 func NewReader(r io.Reader) *Reader {
 	return &Reader{
-		line: 1,
-		fok:  make(map[string]bool),
-		r:    bufio.NewReader(r),
-		b:    &bytes.Buffer{},
+		line:  1,
+		fok:   make(map[string]bool),
+		spell: make(map[string]string),
+		r:     bufio.NewReader(r),
+		b:     &bytes.Buffer{},
 	}
 }
 
+// Line returns the current line number of the reader.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Warnings returns the invalid characters found until the last read call,
+// if Check is set to Warn. Each warning is a *ParseError. The caller should
+// not modify this slice.
+func (r *Reader) Warnings() []error {
+	return r.warns
+}
+
 // Fields returns a sorted list of all the fields read until the last read
 // call. The caller should not modify this slice.
 func (r *Reader) Fields() []string {
 	return r.fields
 }
 
+// Spelling returns the spelling of a field, as found the first time the
+// field was read (e.g. "ISO3166" for the field "iso3166"). If the field was
+// not read, the field name is returned.
+func (r *Reader) Spelling(field string) string {
+	if sp, ok := r.spell[field]; ok {
+		return sp
+	}
+	return field
+}
+
+// Spellings returns a map with the original spelling of each field read
+// until the last read call. The returned map is owned by the caller.
+func (r *Reader) Spellings() map[string]string {
+	m := make(map[string]string, len(r.spell))
+	for f, sp := range r.spell {
+		m[f] = sp
+	}
+	return m
+}
+
+// SetSchema sets the schema used by the reader. If a record read by r does
+// not have a field defined in the schema, and the field has a default value,
+// the default value will be used as the content of the field.
+func (r *Reader) SetSchema(s *Schema) {
+	r.schema = s
+}
+
 // Read reads one record from r. The record is a map in which each entry
 // represents the content of the field indicated by the key. The returned map
 // is owned by the caller.
 func (r *Reader) Read() (record map[string]string, err error) {
+	rec, err := r.read(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: Read")
+	}
+	return rec.Map(), nil
+}
+
+// ReadRecord reads one record from r, keeping the fields in the order in
+// which they were found in the file. Unlike Read, a field can be repeated in
+// the record.
+func (r *Reader) ReadRecord() (Record, error) {
+	rec, err := r.read(true)
+	if err != nil {
+		return nil, errors.Wrap(err, "stanza: ReadRecord")
+	}
+	return rec, nil
+}
+
+// read reads a non-empty record. If repeat is true, repeated fields are
+// accepted.
+func (r *Reader) read(repeat bool) (Record, error) {
+	if !r.bom {
+		r.bom = true
+		r.checkBOM()
+	}
 	for {
-		record, err = r.parseRecord()
+		record, err := r.parseRecord(repeat)
 		if err != nil {
-			return nil, errors.Wrap(err, "stanza: Read")
+			return nil, err
 		}
 		if record == nil {
 			continue
 		}
-		return record, nil
+		return r.setDefaults(record), nil
 	}
 }
 
-// parseRecord parses a single record.
-func (r *Reader) parseRecord() (record map[string]string, err error) {
-	record = make(map[string]string)
+// setDefaults adds the default values of the schema to the fields missing
+// in the record.
+func (r *Reader) setDefaults(record Record) Record {
+	if r.schema == nil {
+		return record
+	}
+	for _, f := range r.schema.Fields {
+		if len(f.Default) == 0 {
+			continue
+		}
+		if _, ok := record.Get(f.Name); ok {
+			continue
+		}
+		record = append(record, Field{Name: f.Name, Value: f.Default})
+		r.addField(f.Name)
+	}
+	return record
+}
+
+// addField adds a field to the list of fields read.
+func (r *Reader) addField(f string) {
+	if r.fok[f] {
+		return
+	}
+	r.fok[f] = true
+	r.fields = append(r.fields, f)
+}
+
+// parseRecord parses a single record. If an error is found in a field, the
+// rest of the record is read, and the first error found is returned. If
+// repeat is true, repeated fields are accepted.
+func (r *Reader) parseRecord(repeat bool) (record Record, err error) {
+	r.term = false
+	var perr error
+	start := 0
 	for {
 		f, delim, err := r.parseFieldName()
 		if err != nil {
-			if len(record) == 0 {
+			if len(record) == 0 && perr == nil && r.cerr == nil {
 				return nil, err
 			}
 			break
@@ -129,69 +309,113 @@ func (r *Reader) parseRecord() (record map[string]string, err error) {
 		if delim == '\n' {
 			continue
 		}
-		if delim == '%' {
+		if delim == eor {
 			break
 		}
+		line, canon, raw := r.fline, r.canon, r.raw
 		v, end := r.parseFieldValue()
+		v = r.Normalize.apply(v)
+		if perr == nil {
+			perr = r.checkField(record, f, v, line, canon, repeat)
+		}
+		if start == 0 {
+			start = line
+		}
 		if len(f) > 0 && len(v) > 0 {
-			if _, dup := record[f]; dup {
-				return nil, errors.Errorf("line: %d: duplicated field %q", r.line, f)
-			}
-			record[f] = v
-			if !r.fok[f] {
-				r.fok[f] = true
-				r.fields = append(r.fields, f)
+			record = append(record, Field{Name: f, Value: v, Spelling: raw})
+			r.addField(f)
+			if _, ok := r.spell[f]; !ok {
+				r.spell[f] = raw
 			}
 		}
 		if end {
 			break
 		}
 	}
+	if perr == nil {
+		perr = r.cerr
+	}
+	r.cerr = nil
+	if perr != nil {
+		return nil, perr
+	}
 	if len(record) == 0 {
 		return nil, nil
 	}
+	if r.Strict && !r.term && !r.Dialect.BlankSeparator {
+		return nil, &ParseError{Line: start, Err: ErrNoTerminator}
+	}
 	return record, nil
 }
 
+// checkField checks a field before it is added to a record.
+func (r *Reader) checkField(record Record, f, v string, line int, canon, repeat bool) error {
+	if !repeat && len(f) > 0 && len(v) > 0 {
+		if _, dup := record.Get(f); dup {
+			return &ParseError{Line: line, Err: errors.Errorf("duplicated field %q", f)}
+		}
+	}
+	if !r.Strict {
+		return nil
+	}
+	if len(f) == 0 {
+		return &ParseError{Line: line, Err: ErrNoName}
+	}
+	if !canon {
+		return &ParseError{Line: line, Err: errors.Errorf("field %q: name not in canonical form", f)}
+	}
+	return nil
+}
+
+// eor is the delimiter returned by parseFieldName at an end-of-record.
+const eor rune = -1
+
 // parseFieldName parses a field name. Delim indicates the character at the
-// end of the field name.
+// end of the field name, or eor if an end-of-record was found.
 func (r *Reader) parseFieldName() (field string, delim rune, err error) {
 	// setup the reading of a field line: ignores lines starting with
 	// comments, and finish if on an end-of-record.
 	for {
-		r1, err := readRune(r.r)
+		if r.atComment() {
+			r.skip('\n') // skip comments
+			r.line++
+			continue
+		}
+		r1, err := r.readRune()
 		if err != nil {
 			return "", 0, err
 		}
 		if !unicode.IsSpace(r1) {
-			if r1 == '#' {
-				skip(r.r, '\n') // skip comments
+			if r.isTerminator(r1) {
+				r.skip('\n') // end-of-record
 				r.line++
-				continue
+				r.term = true
+				return "", eor, nil
 			}
-			if r1 == '%' {
-				skip(r.r, '\n') // end-of-record
-				r.line++
-				return "", '%', nil
-			}
-			r.r.UnreadRune()
+			r.unreadRune()
 			break
 		}
 		if r1 == '\n' {
 			r.line++
+			if r.Dialect.BlankSeparator {
+				r.term = true
+				return "", eor, nil // blank line as end-of-record
+			}
 		}
 	}
 
-	// reads the field name, stop at a colon (:), or with a new line
+	// reads the field name, stop at the separator, or with a new line
 	// (interpreted as an empty field).
 	r.b.Reset()
+	r.fline = r.line
+	sep := r.Dialect.separator()
 	space := false
 	for {
-		r1, err := readRune(r.r)
+		r1, err := r.readRune()
 		if err != nil {
 			return "", 0, err
 		}
-		if r1 == ':' || r1 == '\n' {
+		if r1 == sep || r1 == '\n' {
 			if r1 == '\n' {
 				r.line++
 			}
@@ -202,14 +426,20 @@ func (r *Reader) parseFieldName() (field string, delim rune, err error) {
 			space = true
 			continue
 		}
-		// replace spaces with '-' character
 		if space {
 			space = false
-			r.b.WriteRune('-')
+			r.b.WriteRune(' ')
 		}
 		r.b.WriteRune(r1)
 	}
-	return strings.ToLower(r.b.String()), delim, nil
+	r.raw = r.b.String()
+	field = r.Dialect.fieldName(r.raw)
+	if r.FoldCase {
+		field = foldCase(field)
+	}
+	field = r.Normalize.apply(field)
+	r.canon = field == r.raw
+	return field, delim, nil
 }
 
 // parseFieldValue parses the value of a field. End indicates that the end-of-
@@ -219,7 +449,7 @@ func (r *Reader) parseFieldValue() (value string, end bool) {
 	r.b.Reset()
 	space, first, line := false, true, false
 	for {
-		r1, err := readRune(r.r)
+		r1, err := r.readRune()
 		if err != nil {
 			end = true
 			break
@@ -229,30 +459,43 @@ func (r *Reader) parseFieldValue() (value string, end bool) {
 		if r1 == '\n' {
 			r.line++
 			space, line = false, true
-			r1, err = readRune(r.r)
+			for r.atComment() {
+				r.skip('\n') // skip comments
+				r.line++
+			}
+			r1, err = r.readRune()
 			if err != nil {
 				end = true
 				break
 			}
-			if r1 == '#' {
-				skip(r.r, '\n') // skip comments
-				r.line++
-				continue
-			}
-			if r1 == '%' {
+			if r.isTerminator(r1) {
 				end = true
-				skip(r.r, '\n') // end-of-record
+				r.skip('\n') // end-of-record
 				r.line++
+				r.term = true
 				break
 			}
 			if r1 == '\n' {
-				r.r.UnreadRune() // make decision on next loop
+				r.unreadRune() // make decision on next loop
+				if r.Dialect.BlankSeparator {
+					break // end-of-field and end-of-record
+				}
 				continue
 			}
 			if unicode.IsSpace(r1) {
+				if !r.Dialect.BlankSeparator && !r.Dialect.DotBlank {
+					continue // multiline field
+				}
+				blank, dot := r.indent()
+				if blank && r.Dialect.BlankSeparator {
+					break // end-of-field and end-of-record
+				}
+				if dot && !first {
+					r.b.WriteRune('\n') // encoded blank line
+				}
 				continue // multiline field
 			}
-			r.r.UnreadRune() // end-of-field
+			r.unreadRune() // end-of-field
 			break
 		}
 		if unicode.IsSpace(r1) {
@@ -276,27 +519,89 @@ func (r *Reader) parseFieldValue() (value string, end bool) {
 	return r.b.String(), end
 }
 
+// atComment returns true if the reader is at the start of a comment.
+func (r *Reader) atComment() bool {
+	for _, p := range r.Dialect.comments() {
+		if b, _ := r.r.Peek(len(p)); string(b) == p {
+			return true
+		}
+	}
+	return false
+}
+
+// isTerminator returns true if r1 is an end-of-record mark.
+func (r *Reader) isTerminator(r1 rune) bool {
+	if r.Dialect.BlankSeparator && r.Dialect.Terminator == 0 {
+		return false
+	}
+	return r1 == r.Dialect.terminator()
+}
+
+// indent reads the indentation of a continuation line. Blank is true if the
+// line has only spaces, and dot is true if the line is an encoded blank line
+// (a single '.' character). In both cases the reader is positioned at the
+// end of the line, otherwise it is positioned at the first character of the
+// line content.
+func (r *Reader) indent() (blank, dot bool) {
+	for {
+		r1, err := r.readRune()
+		if err != nil {
+			return true, false
+		}
+		if r1 == '\n' {
+			r.unreadRune()
+			return true, false
+		}
+		if unicode.IsSpace(r1) {
+			continue
+		}
+		r.unreadRune()
+		if r1 != '.' || !r.Dialect.DotBlank {
+			return false, false
+		}
+		b, _ := r.r.Peek(3)
+		if len(b) == 1 || b[1] == '\n' || (b[1] == '\r' && len(b) > 2 && b[2] == '\n') {
+			r.readRune()
+			return false, true
+		}
+		return false, false
+	}
+}
+
 // readRune reads a rune, folding \r\n to \n.
-func readRune(r *bufio.Reader) (rune, error) {
-	r1, _, err := r.ReadRune()
+func (r *Reader) readRune() (rune, error) {
+	r1, size, err := r.r.ReadRune()
+	if err != nil {
+		return r1, err
+	}
 
 	// handle \r\n
 	if r1 == '\r' {
-		r1, _, err = r.ReadRune()
-		if err != nil {
-			if r1 != '\n' {
-				r.UnreadRune()
-				r1 = '\r'
-			}
+		if b, _ := r.r.Peek(1); len(b) == 1 && b[0] == '\n' {
+			r1, size, _ = r.r.ReadRune()
 		}
 	}
-	return r1, err
+
+	r.pcol = r.col
+	if r1 == '\n' {
+		r.col = 0
+		return r1, nil
+	}
+	r.col++
+	r.checkRune(r1, size)
+	return r1, nil
+}
+
+// unreadRune unreads the last rune.
+func (r *Reader) unreadRune() {
+	r.r.UnreadRune()
+	r.col = r.pcol
 }
 
 // skip read runes up to and including the rune delim or until error.
-func skip(r *bufio.Reader, delim rune) error {
+func (r *Reader) skip(delim rune) error {
 	for {
-		r1, err := readRune(r)
+		r1, err := r.readRune()
 		if err != nil {
 			return err
 		}
@@ -305,3 +610,34 @@ func skip(r *bufio.Reader, delim rune) error {
 		}
 	}
 }
+
+// checkRune checks if a rune is a valid character.
+func (r *Reader) checkRune(r1 rune, size int) {
+	if r.Check == Ignore {
+		return
+	}
+	var err error
+	switch {
+	case r1 == utf8.RuneError && size == 1:
+		err = ErrInvalidUTF8
+	case unicode.IsControl(r1) && r1 != '\t' && r1 != '\r':
+		err = ErrControl
+	default:
+		return
+	}
+
+	// the rune was already checked
+	if r.line < r.cline || (r.line == r.cline && r.col <= r.ccol) {
+		return
+	}
+	r.cline, r.ccol = r.line, r.col
+
+	pe := &ParseError{Line: r.line, Column: r.col, Err: err}
+	if r.Check == Warn {
+		r.warns = append(r.warns, pe)
+		return
+	}
+	if r.cerr == nil {
+		r.cerr = pe
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package recfile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/js-arias/stanza"
+)
+
+// A Descriptor is a record descriptor.
+type Descriptor struct {
+	Type      string            // record type (%rec)
+	Doc       string            // documentation (%doc)
+	Key       string            // primary key (%key)
+	Mandatory []string          // mandatory fields (%mandatory)
+	Types     map[string]string // type description of the fields (%type)
+	Other     stanza.Record     // other descriptor fields (e.g. %sort)
+}
+
+// newDescriptor returns a descriptor from a descriptor record.
+func newDescriptor(rec stanza.Record) *Descriptor {
+	d := &Descriptor{Types: make(map[string]string)}
+	for _, f := range rec {
+		v := strings.TrimSpace(f.Value)
+		switch f.Name {
+		case "%rec":
+			if fs := strings.Fields(v); len(fs) > 0 {
+				d.Type = fs[0]
+			}
+		case "%doc":
+			d.Doc = v
+		case "%key":
+			d.Key = v
+		case "%mandatory":
+			d.Mandatory = append(d.Mandatory, strings.Fields(v)...)
+		case "%type":
+			fs := strings.Fields(v)
+			if len(fs) < 2 {
+				continue
+			}
+			tp := strings.Join(fs[1:], " ")
+			for _, n := range strings.Split(fs[0], ",") {
+				if len(n) > 0 {
+					d.Types[n] = tp
+				}
+			}
+		default:
+			d.Other = append(d.Other, f)
+		}
+	}
+	return d
+}
+
+// record returns the descriptor as a record.
+func (d *Descriptor) record() stanza.Record {
+	rec := stanza.Record{{Name: "%rec", Value: d.Type}}
+	if len(d.Doc) > 0 {
+		rec = append(rec, stanza.Field{Name: "%doc", Value: d.Doc})
+	}
+	if len(d.Key) > 0 {
+		rec = append(rec, stanza.Field{Name: "%key", Value: d.Key})
+	}
+	if len(d.Mandatory) > 0 {
+		rec = append(rec, stanza.Field{Name: "%mandatory", Value: strings.Join(d.Mandatory, " ")})
+	}
+	var names []string
+	for n := range d.Types {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		rec = append(rec, stanza.Field{Name: "%type", Value: n + " " + d.Types[n]})
+	}
+	return append(rec, d.Other...)
+}
+
+// Schema returns the schema defined by the descriptor. Only fields with a
+// type, the mandatory fields and the key are included in the schema.
+func (d *Descriptor) Schema() *stanza.Schema {
+	s := &stanza.Schema{}
+	add := func(name string) *stanza.FieldSchema {
+		if f := s.Field(name); f != nil {
+			return f
+		}
+		f := &stanza.FieldSchema{Name: name}
+		s.Fields = append(s.Fields, f)
+		return f
+	}
+	if len(d.Key) > 0 {
+		f := add(d.Key)
+		f.Presence = 1
+	}
+	for _, n := range d.Mandatory {
+		f := add(n)
+		f.Presence = 1
+	}
+	var names []string
+	for n := range d.Types {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		f := add(n)
+		f.Type = schemaType(d.Types[n])
+	}
+	return s
+}
+
+// NewDescriptor returns a descriptor for the records of the given type,
+// using a schema. Fields present in all the records are set as mandatory,
+// and fields with a non text type are set with the equivalent recfile type.
+func NewDescriptor(recType string, s *stanza.Schema) *Descriptor {
+	d := &Descriptor{
+		Type:  recType,
+		Types: make(map[string]string),
+	}
+	for _, f := range s.Fields {
+		name := fieldName(f.Name)
+		if f.Presence >= 1 {
+			d.Mandatory = append(d.Mandatory, name)
+		}
+		if tp := typeDesc(f.Type); len(tp) > 0 {
+			d.Types[name] = tp
+		}
+	}
+	return d
+}
+
+// schemaType returns the schema type of a recfile type description.
+func schemaType(desc string) stanza.Type {
+	fs := strings.Fields(desc)
+	if len(fs) == 0 {
+		return stanza.Text
+	}
+	switch fs[0] {
+	case "int", "range", "size":
+		return stanza.Integer
+	case "real":
+		return stanza.Float
+	case "bool":
+		return stanza.Boolean
+	case "date":
+		return stanza.Date
+	}
+	return stanza.Text
+}
+
+// typeDesc returns the recfile type of a schema type. It returns an empty
+// string for text fields.
+func typeDesc(t stanza.Type) string {
+	switch t {
+	case stanza.Integer:
+		return "int"
+	case stanza.Float:
+		return "real"
+	case stanza.Boolean:
+		return "bool"
+	case stanza.Date:
+		return "date"
+	}
+	return ""
+}
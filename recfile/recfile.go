@@ -0,0 +1,288 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package recfile reads and writes GNU recutils files (recfiles).
+//
+// Recfiles are a close relative of the stanza format, with the following
+// differences:
+//
+//	1- Records are separated by one or more blank lines, instead of an
+//	   end-of-record mark.
+//	2- Field names are case sensitive, must start with a letter, and
+//	   contain only letters, digits and '_'.
+//	3- A multi-line value continues in the next line if the line starts
+//	   with '+' character, or if the line ends with a backslash.
+//	4- Records whose first field name starts with '%' are record
+//	   descriptors, that define the type of the following records.
+//
+// Records are read and written as stanza records, and descriptors can be
+// converted from and to a stanza schema.
+//
+// For a full description of the format see
+// <https://www.gnu.org/software/recutils/manual/>.
+package recfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+// A Reader reads records from a recfile.
+type Reader struct {
+	line int
+	desc *Descriptor
+	s    *bufio.Scanner
+	next string // a line already read
+	ok   bool   // there is a line already read
+}
+
+// NewReader returns a new Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<24)
+	return &Reader{s: s}
+}
+
+// Descriptor returns the record descriptor of the last record read. It
+// returns nil if the record does not have a descriptor.
+func (r *Reader) Descriptor() *Descriptor {
+	return r.desc
+}
+
+// Read reads a record from r. Record descriptors are not returned, but they
+// are stored, and can be retrieved with the Descriptor method.
+func (r *Reader) Read() (stanza.Record, error) {
+	for {
+		rec, err := r.parseRecord()
+		if err != nil {
+			return nil, errors.Wrap(err, "recfile: Read")
+		}
+		if len(rec[0].Name) > 0 && rec[0].Name[0] == '%' {
+			r.desc = newDescriptor(rec)
+			continue
+		}
+		return rec, nil
+	}
+}
+
+// readLine reads a line from the input.
+func (r *Reader) readLine() (string, bool) {
+	if r.ok {
+		r.ok = false
+		return r.next, true
+	}
+	if !r.s.Scan() {
+		return "", false
+	}
+	r.line++
+	return strings.TrimRight(r.s.Text(), "\r"), true
+}
+
+// unreadLine returns a line to the input.
+func (r *Reader) unreadLine(ln string) {
+	r.next, r.ok = ln, true
+}
+
+// parseRecord parses a single record.
+func (r *Reader) parseRecord() (stanza.Record, error) {
+	var rec stanza.Record
+	for {
+		ln, ok := r.readLine()
+		if !ok {
+			if err := r.s.Err(); err != nil {
+				return nil, err
+			}
+			if len(rec) == 0 {
+				return nil, io.EOF
+			}
+			return rec, nil
+		}
+		if strings.HasPrefix(ln, "#") {
+			continue
+		}
+		if len(strings.TrimSpace(ln)) == 0 {
+			if len(rec) == 0 {
+				continue
+			}
+			return rec, nil
+		}
+
+		i := strings.Index(ln, ":")
+		if i < 0 {
+			return nil, &stanza.ParseError{Line: r.line, Err: errors.New("expecting a field")}
+		}
+		name := ln[:i]
+		if !validName(name) {
+			return nil, &stanza.ParseError{Line: r.line, Err: errors.Errorf("invalid field name %q", name)}
+		}
+		v := ln[i+1:]
+		if len(v) > 0 && (v[0] == ' ' || v[0] == '\t') {
+			v = v[1:]
+		}
+		b := &bytes.Buffer{}
+		r.value(b, v)
+		for {
+			ln, ok := r.readLine()
+			if !ok {
+				break
+			}
+			if !strings.HasPrefix(ln, "+") {
+				r.unreadLine(ln)
+				break
+			}
+			ln = ln[1:]
+			if len(ln) > 0 && (ln[0] == ' ' || ln[0] == '\t') {
+				ln = ln[1:]
+			}
+			b.WriteByte('\n')
+			r.value(b, ln)
+		}
+		rec = append(rec, stanza.Field{Name: name, Value: b.String()})
+	}
+}
+
+// value adds the content of a line to a value, reading the following lines
+// if the line ends with a backslash.
+func (r *Reader) value(b *bytes.Buffer, ln string) {
+	for strings.HasSuffix(ln, "\\") {
+		b.WriteString(ln[:len(ln)-1])
+		next, ok := r.readLine()
+		if !ok {
+			return
+		}
+		ln = next
+	}
+	b.WriteString(ln)
+}
+
+// validName returns true if name is a valid field name.
+func validName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i, r1 := range name {
+		if i == 0 && r1 == '%' {
+			continue
+		}
+		if r1 > unicode.MaxASCII {
+			return false
+		}
+		if unicode.IsLetter(r1) {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r1) || r1 == '_') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// A Writer writes records to a recfile.
+type Writer struct {
+	w *bufio.Writer
+	n int // number of records written
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes a record to w. Field names are written with the characters
+// not allowed in a recfile field name (e.g. '-') replaced by '_', and
+// fields without content are not written.
+func (w *Writer) Write(rec stanza.Record) error {
+	if err := w.write(rec); err != nil {
+		return errors.Wrap(err, "recfile: Write")
+	}
+	return nil
+}
+
+// WriteDescriptor writes a record descriptor to w. The descriptor applies
+// to all the records written after it.
+func (w *Writer) WriteDescriptor(d *Descriptor) error {
+	if err := w.write(d.record()); err != nil {
+		return errors.Wrap(err, "recfile: WriteDescriptor")
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	if err := w.w.Flush(); err != nil {
+		return errors.Wrap(err, "recfile: Flush")
+	}
+	return nil
+}
+
+func (w *Writer) write(rec stanza.Record) error {
+	first := true
+	for _, f := range rec {
+		v := strings.TrimSpace(f.Value)
+		if len(v) == 0 {
+			continue
+		}
+		name := fieldName(f.Name)
+		if len(name) == 0 {
+			continue
+		}
+		if first {
+			if w.n > 0 {
+				if _, err := w.w.WriteString("\n"); err != nil {
+					return err
+				}
+			}
+			first = false
+		}
+		v = strings.Replace(v, "\r", "", -1)
+		v = escapeLines(v)
+		if _, err := w.w.WriteString(name + ": " + v + "\n"); err != nil {
+			return err
+		}
+	}
+	if !first {
+		w.n++
+	}
+	return nil
+}
+
+// escapeLines formats a value as a sequence of lines and continuation
+// lines. A line ending in a backslash is followed by an additional
+// backslash and an empty line, so it is not joined with the next line when
+// read.
+func escapeLines(v string) string {
+	lines := strings.Split(v, "\n")
+	for i, ln := range lines {
+		if strings.HasSuffix(ln, "\\") {
+			lines[i] = ln + "\\\n"
+		}
+	}
+	return strings.Join(lines, "\n+ ")
+}
+
+// fieldName returns a valid recfile field name.
+func fieldName(name string) string {
+	b := &bytes.Buffer{}
+	for i, r1 := range name {
+		if i == 0 && r1 == '%' {
+			b.WriteRune(r1)
+			continue
+		}
+		if r1 <= unicode.MaxASCII && (unicode.IsLetter(r1) || (b.Len() > 0 && unicode.IsDigit(r1))) {
+			b.WriteRune(r1)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
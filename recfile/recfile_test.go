@@ -0,0 +1,124 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package recfile
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/stanza"
+	"github.com/pkg/errors"
+)
+
+var blob = `# Country data facts
+%rec: Country
+%key: ISO3166
+%mandatory: Name
+%type: Population int
+
+Name: República Argentina
+ISO3166: AR
+Population: 42669500
+Anthem: Ya su trono dignísimo abrieron
++ las Provincias Unidas del Sud
+
+Name: 中华人民共和国
+ISO3166: CN
+Capital: Bei\
+jing
+Population: 1339724852
+`
+
+func TestRead(t *testing.T) {
+	r := NewReader(strings.NewReader(blob))
+	var recs []stanza.Record
+	for {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("read: found %d records, want 2", len(recs))
+	}
+	if v, _ := recs[0].Get("Anthem"); v != "Ya su trono dignísimo abrieron\nlas Provincias Unidas del Sud" {
+		t.Errorf("read: multi-line field: found %q", v)
+	}
+	if v, _ := recs[1].Get("Capital"); v != "Beijing" {
+		t.Errorf("read: continued line: found %q", v)
+	}
+
+	d := r.Descriptor()
+	if d == nil || d.Type != "Country" || d.Key != "ISO3166" {
+		t.Fatalf("read: descriptor %v", d)
+	}
+	s := d.Schema()
+	if f := s.Field("Population"); f == nil || f.Type != stanza.Integer {
+		t.Errorf("read: schema: field %q: %v", "Population", f)
+	}
+	if f := s.Field("Name"); f == nil || f.Presence != 1 {
+		t.Errorf("read: schema: field %q: %v", "Name", f)
+	}
+
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.WriteDescriptor(d)
+	for _, rec := range recs {
+		w.Write(rec)
+	}
+	w.Flush()
+	r = NewReader(out)
+	for i := 0; ; i++ {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if len(rec) != len(recs[i]) {
+			t.Fatalf("write: record %d: found %d fields, want %d", i, len(rec), len(recs[i]))
+		}
+		for j, f := range rec {
+			if f != recs[i][j] {
+				t.Errorf("write: record %d: found %v, want %v", i, f, recs[i][j])
+			}
+		}
+	}
+	if nd := r.Descriptor(); nd == nil || nd.Key != d.Key || nd.Types["Population"] != "int" {
+		t.Errorf("write: descriptor %v", nd)
+	}
+}
+
+func TestBackslash(t *testing.T) {
+	rec := stanza.Record{
+		{Name: "path", Value: `C:\dir\`},
+		{Name: "list", Value: "a\\\nb\\"},
+		{Name: "next", Value: "x"},
+	}
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.Write(rec)
+	w.Flush()
+	r := NewReader(out)
+	got, err := r.Read()
+	if err != nil {
+		t.Fatalf("backslash: %v", err)
+	}
+	if len(got) != len(rec) {
+		t.Fatalf("backslash: found %v, want %v", got, rec)
+	}
+	for i, f := range got {
+		if f != rec[i] {
+			t.Errorf("backslash: found %v, want %v", f, rec[i])
+		}
+	}
+}
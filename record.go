@@ -0,0 +1,90 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import "strings"
+
+// A Field is a named field of a record.
+type Field struct {
+	Name     string
+	Value    string
+	Spelling string // name as found in the file, if known
+}
+
+// A RecordReader reads records. It is implemented by Reader, and by the
+// readers that transform the records of another RecordReader, so they can
+// be composed.
+type RecordReader interface {
+	// ReadRecord reads one record. At the end of the input it returns
+	// io.EOF.
+	ReadRecord() (Record, error)
+}
+
+// A Record is an ordered list of fields. Unlike the map representation, a
+// record keeps the order of the fields, and a field can be repeated.
+type Record []Field
+
+// Get returns the content of the first field with the given name. Ok is
+// false if the field is not in the record.
+func (rec Record) Get(name string) (value string, ok bool) {
+	for _, f := range rec {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Lookup returns the content of the first field whose name matches the given
+// name, ignoring case, and considering spaces as '-'.
+func (rec Record) Lookup(name string) (value string, ok bool) {
+	name = strings.Join(strings.Fields(name), "-")
+	for _, f := range rec {
+		if strings.EqualFold(strings.Join(strings.Fields(f.Name), "-"), name) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns the content of all the fields with the given name, in the
+// record order.
+func (rec Record) Values(name string) []string {
+	var vs []string
+	for _, f := range rec {
+		if f.Name == name {
+			vs = append(vs, f.Value)
+		}
+	}
+	return vs
+}
+
+// Names returns the names of the fields of the record, without repetitions,
+// in the order of their first occurrence.
+func (rec Record) Names() []string {
+	ok := make(map[string]bool, len(rec))
+	var names []string
+	for _, f := range rec {
+		if ok[f.Name] {
+			continue
+		}
+		ok[f.Name] = true
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Map returns the record as a map. If a field is repeated, only the first
+// value is stored in the map.
+func (rec Record) Map() map[string]string {
+	m := make(map[string]string, len(rec))
+	for _, f := range rec {
+		if _, dup := m[f.Name]; dup {
+			continue
+		}
+		m[f.Name] = f.Value
+	}
+	return m
+}
@@ -0,0 +1,315 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Type is the type of the content of a field.
+type Type int
+
+// Valid field types.
+const (
+	Text Type = iota
+	Integer
+	Float
+	Date
+	Boolean
+)
+
+var typeNames = []string{
+	Text:    "text",
+	Integer: "integer",
+	Float:   "float",
+	Date:    "date",
+	Boolean: "boolean",
+}
+
+// String returns the name of a type.
+func (t Type) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return "type(" + strconv.Itoa(int(t)) + ")"
+	}
+	return typeNames[t]
+}
+
+// ParseType returns the type with the given name.
+func ParseType(name string) (Type, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, n := range typeNames {
+		if n == name {
+			return Type(i), nil
+		}
+	}
+	return Text, errors.Errorf("stanza: ParseType: unknown type %q", name)
+}
+
+// DateLayouts are the layouts used to detect a date value.
+var DateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+}
+
+// A FieldSchema describes the content of a field.
+type FieldSchema struct {
+	Name        string
+	Type        Type
+	Presence    float64 // fraction of records with the field
+	Cardinality int     // number of distinct values
+	MaxLength   int     // maximum length in characters
+	Multiline   bool    // true if some value has more than one line
+	Default     string  // value used when the field is missing
+}
+
+// A Schema describes the fields of a stanza file.
+type Schema struct {
+	Fields []*FieldSchema
+}
+
+// Field returns the description of the given field, or nil if the field is
+// not in the schema.
+func (s *Schema) Field(name string) *FieldSchema {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// schemaFields are the fields used to write a schema.
+var schemaFields = []string{"field", "type", "presence", "cardinality", "max-length", "multiline", "default"}
+
+// Write writes the schema to w, as a stanza list, with a record for each
+// field.
+func (s *Schema) Write(w io.Writer) error {
+	sw := NewWriter(w)
+	sw.SetFields(schemaFields)
+	for _, f := range s.Fields {
+		rec := map[string]string{
+			"field":       f.Name,
+			"type":        f.Type.String(),
+			"presence":    strconv.FormatFloat(f.Presence, 'f', -1, 64),
+			"cardinality": strconv.Itoa(f.Cardinality),
+			"max-length":  strconv.Itoa(f.MaxLength),
+			"multiline":   strconv.FormatBool(f.Multiline),
+			"default":     f.Default,
+		}
+		if err := sw.Write(rec); err != nil {
+			return errors.Wrap(err, "stanza: Schema: Write")
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return errors.Wrap(err, "stanza: Schema: Write")
+	}
+	return nil
+}
+
+// ReadSchema reads a schema written with Schema.Write.
+func ReadSchema(r io.Reader) (*Schema, error) {
+	sr := NewReader(r)
+	s := &Schema{}
+	for {
+		rec, err := sr.Read()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "stanza: ReadSchema")
+		}
+		f := &FieldSchema{Name: rec["field"]}
+		if f.Name == "" {
+			return nil, errors.Errorf("stanza: ReadSchema: line %d: record without field name", sr.line)
+		}
+		if s.Field(f.Name) != nil {
+			return nil, errors.Errorf("stanza: ReadSchema: line %d: duplicated field %q", sr.line, f.Name)
+		}
+		if v, ok := rec["type"]; ok {
+			if f.Type, err = ParseType(v); err != nil {
+				return nil, errors.Wrapf(err, "stanza: ReadSchema: field %q", f.Name)
+			}
+		}
+		if v, ok := rec["presence"]; ok {
+			if f.Presence, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, errors.Wrapf(err, "stanza: ReadSchema: field %q: presence", f.Name)
+			}
+		}
+		if v, ok := rec["cardinality"]; ok {
+			if f.Cardinality, err = strconv.Atoi(v); err != nil {
+				return nil, errors.Wrapf(err, "stanza: ReadSchema: field %q: cardinality", f.Name)
+			}
+		}
+		if v, ok := rec["max-length"]; ok {
+			if f.MaxLength, err = strconv.Atoi(v); err != nil {
+				return nil, errors.Wrapf(err, "stanza: ReadSchema: field %q: max-length", f.Name)
+			}
+		}
+		if v, ok := rec["multiline"]; ok {
+			if f.Multiline, err = strconv.ParseBool(v); err != nil {
+				return nil, errors.Wrapf(err, "stanza: ReadSchema: field %q: multiline", f.Name)
+			}
+		}
+		f.Default = rec["default"]
+		s.Fields = append(s.Fields, f)
+	}
+	return s, nil
+}
+
+// fieldStats accumulates the values of a field during schema inference.
+type fieldStats struct {
+	count    int // number of records with the field
+	values   map[string]bool
+	maxLen   int
+	multi    bool
+	notInt   bool
+	notFloat bool
+	notDate  bool
+	notBool  bool
+}
+
+func (st *fieldStats) add(v string) {
+	st.values[v] = true
+	if n := utf8.RuneCountInString(v); n > st.maxLen {
+		st.maxLen = n
+	}
+	if strings.Contains(v, "\n") {
+		st.multi = true
+	}
+	if !st.notInt {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			st.notInt = true
+		}
+	}
+	if !st.notFloat {
+		if _, err := parseNumber(v); err != nil {
+			st.notFloat = true
+		}
+	}
+	if !st.notBool {
+		if _, err := parseBool(v); err != nil {
+			st.notBool = true
+		}
+	}
+	if !st.notDate {
+		if _, err := parseDate(v); err != nil {
+			st.notDate = true
+		}
+	}
+}
+
+// kind returns the inferred type of the field.
+func (st *fieldStats) kind() Type {
+	switch {
+	case st.multi:
+		return Text
+	case !st.notInt:
+		return Integer
+	case !st.notFloat:
+		return Float
+	case !st.notBool:
+		return Boolean
+	case !st.notDate:
+		return Date
+	}
+	return Text
+}
+
+// InferSchema reads all the records from r and returns the schema inferred
+// from the content of the records. The fields of the schema are in the
+// order in which they were found.
+func InferSchema(r RecordReader) (*Schema, error) {
+	stats := make(map[string]*fieldStats)
+	var fields []string
+	n := 0
+	for {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "stanza: InferSchema")
+		}
+		n++
+		for _, f := range rec.Names() {
+			st, ok := stats[f]
+			if !ok {
+				st = &fieldStats{values: make(map[string]bool)}
+				stats[f] = st
+				fields = append(fields, f)
+			}
+			st.count++
+			for _, v := range rec.Values(f) {
+				st.add(v)
+			}
+		}
+	}
+
+	s := &Schema{}
+	for _, f := range fields {
+		st := stats[f]
+		s.Fields = append(s.Fields, &FieldSchema{
+			Name:        f,
+			Type:        st.kind(),
+			Presence:    float64(st.count) / float64(n),
+			Cardinality: len(st.values),
+			MaxLength:   st.maxLen,
+			Multiline:   st.multi,
+		})
+	}
+	return s, nil
+}
+
+// parseBool parses a boolean value.
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "t", "y":
+		return true, nil
+	case "false", "no", "f", "n":
+		return false, nil
+	}
+	return false, errors.Errorf("invalid boolean value %q", v)
+}
+
+// parseNumber parses a decimal number (e.g. "12", "-0.5" or "1e3"). Unlike
+// strconv.ParseFloat, special values (e.g. "NaN" or "Inf") and hexadecimal
+// forms are not accepted.
+func parseNumber(v string) (float64, error) {
+	digit := false
+	for _, r := range v {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case strings.ContainsRune("+-.eE", r):
+		default:
+			return 0, errors.Errorf("invalid number %q", v)
+		}
+	}
+	if !digit {
+		return 0, errors.Errorf("invalid number %q", v)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid number %q", v)
+	}
+	return f, nil
+}
+
+// parseDate parses a date value using DateLayouts.
+func parseDate(v string) (time.Time, error) {
+	for _, l := range DateLayouts {
+		if t, err := time.Parse(l, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid date value %q", v)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	s, err := InferSchema(NewReader(strings.NewReader(blob)))
+	if err != nil {
+		t.Fatalf("infer schema: %v", err)
+	}
+	if len(s.Fields) != 6 {
+		t.Errorf("infer schema: expecting 6 fields, found %d", len(s.Fields))
+	}
+	tests := []struct {
+		field     string
+		tp        Type
+		presence  float64
+		multiline bool
+	}{
+		{"name", Text, 1, false},
+		{"population", Integer, 1, false},
+		{"anthem", Text, 0.75, true},
+	}
+	for _, test := range tests {
+		f := s.Field(test.field)
+		if f == nil {
+			t.Errorf("infer schema: field %q not found", test.field)
+			continue
+		}
+		if f.Type != test.tp {
+			t.Errorf("infer schema: field %q: type %v, want %v", test.field, f.Type, test.tp)
+		}
+		if f.Presence != test.presence {
+			t.Errorf("infer schema: field %q: presence %v, want %v", test.field, f.Presence, test.presence)
+		}
+		if f.Multiline != test.multiline {
+			t.Errorf("infer schema: field %q: multiline %v, want %v", test.field, f.Multiline, test.multiline)
+		}
+	}
+	if f := s.Field("iso3166"); f.Cardinality != 4 || f.MaxLength != 2 {
+		t.Errorf("infer schema: field %q: cardinality %d, max length %d", "iso3166", f.Cardinality, f.MaxLength)
+	}
+
+	out := &bytes.Buffer{}
+	if err := s.Write(out); err != nil {
+		t.Fatalf("infer schema: write: %v", err)
+	}
+	ns, err := ReadSchema(out)
+	if err != nil {
+		t.Fatalf("infer schema: read: %v", err)
+	}
+	if len(ns.Fields) != len(s.Fields) {
+		t.Fatalf("infer schema: read %d fields, want %d", len(ns.Fields), len(s.Fields))
+	}
+	for i, f := range s.Fields {
+		if *ns.Fields[i] != *f {
+			t.Errorf("infer schema: read %v, want %v", *ns.Fields[i], *f)
+		}
+	}
+}
+
+func TestInferFloat(t *testing.T) {
+	tests := []struct {
+		data string
+		tp   Type
+	}{
+		{"v: 1.5\n%%\nv: -2e3\n%%\n", Float},
+		{"v: 1.5\n%%\nv: NaN\n%%\n", Text},
+		{"v: Inf\n%%\n", Text},
+		{"v: 0x1p-2\n%%\n", Text},
+	}
+	for _, test := range tests {
+		s, err := InferSchema(NewReader(strings.NewReader(test.data)))
+		if err != nil {
+			t.Fatalf("infer float: %q: %v", test.data, err)
+		}
+		if f := s.Field("v"); f == nil || f.Type != test.tp {
+			t.Errorf("infer float: %q: found %v, want %v", test.data, f, test.tp)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	s := &Schema{Fields: []*FieldSchema{
+		{Name: "anthem", Default: "unknown"},
+		{Name: "continent", Default: "Earth"},
+	}}
+	r := NewReader(strings.NewReader(blob))
+	r.SetSchema(s)
+	i := 0
+	for {
+		rec, err := r.Read()
+		if err != nil {
+			break
+		}
+		if rec["continent"] != "Earth" {
+			t.Errorf("defaults: field %q: found %q", "continent", rec["continent"])
+		}
+		if rec["common"] == "China" && rec["anthem"] != "unknown" {
+			t.Errorf("defaults: field %q: found %q", "anthem", rec["anthem"])
+		}
+		if rec["common"] == "Russia" && rec["anthem"] == "unknown" {
+			t.Errorf("defaults: field %q: default value used on a defined field", "anthem")
+		}
+		i++
+	}
+	if i != 4 {
+		t.Errorf("defaults: expecting 4 records, found: %d", i)
+	}
+}
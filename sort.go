@@ -0,0 +1,358 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// A SortMode defines how the values of a field are compared.
+type SortMode int
+
+// Valid sort modes.
+const (
+	// Values are compared as strings, byte by byte.
+	StringSort SortMode = iota
+
+	// Values are compared as numbers. Values that are not decimal
+	// numbers (including "NaN" or "Inf") are sorted as strings before
+	// the numbers.
+	NumericSort
+
+	// Values are compared as strings, but sequences of digits are
+	// compared as numbers (e.g. "a2" before "a10").
+	NaturalSort
+
+	// Values are compared using the collation rules of a language.
+	LocaleSort
+)
+
+// A SortKey is a field used to sort records. If a field is repeated in a
+// record, only its first value is used. A missing field has an empty
+// value.
+type SortKey struct {
+	Field  string
+	Mode   SortMode
+	Desc   bool   // sort in descending order
+	Locale string // language tag used by LocaleSort (e.g. "es")
+}
+
+// DefaultRunSize is the default number of records sorted in memory.
+const DefaultRunSize = 100000
+
+// A Sorter sorts the records of a file. If the input has more records than
+// RunSize, the records are sorted in runs of RunSize records that are
+// stored in temporary files, and then merged.
+type Sorter struct {
+	Keys    []SortKey
+	RunSize int    // if zero, DefaultRunSize is used
+	TempDir string // directory for temporary files; if empty, os.TempDir
+}
+
+// Sort reads all the records from r and writes them into w, sorted by the
+// given keys. The sort is stable.
+func Sort(w *Writer, r RecordReader, keys ...SortKey) error {
+	s := &Sorter{Keys: keys}
+	return s.Sort(w, r)
+}
+
+// Sort reads all the records from r and writes them into w, sorted by the
+// keys of the sorter. The sort is stable.
+func (s *Sorter) Sort(w *Writer, r RecordReader) error {
+	if err := s.sort(w, r); err != nil {
+		return errors.Wrap(err, "stanza: Sort")
+	}
+	return nil
+}
+
+func (s *Sorter) sort(w *Writer, r RecordReader) error {
+	cmp := newComparer(s.Keys)
+	size := s.RunSize
+	if size <= 0 {
+		size = DefaultRunSize
+	}
+
+	var runs []string
+	defer func() {
+		for _, f := range runs {
+			os.Remove(f)
+		}
+	}()
+	for {
+		recs, err := readRun(r, size)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(recs, func(i, j int) bool {
+			return cmp.compare(recs[i], recs[j]) < 0
+		})
+		if len(runs) == 0 && len(recs) < size {
+			// all records fit in memory
+			for _, rec := range recs {
+				if err := w.WriteRecord(rec); err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		}
+		if len(recs) == 0 {
+			break
+		}
+		name, err := s.writeRun(recs)
+		if len(name) > 0 {
+			runs = append(runs, name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(recs) < size {
+			break
+		}
+	}
+	return merge(w, runs, cmp)
+}
+
+// readRun reads up to size records.
+func readRun(r RecordReader, size int) ([]Record, error) {
+	var recs []Record
+	for len(recs) < size {
+		rec, err := r.ReadRecord()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// writeRun writes a run of sorted records to a temporary file. The records
+// are gob encoded, so they are read back without changes.
+func (s *Sorter) writeRun(recs []Record) (string, error) {
+	f, err := ioutil.TempFile(s.TempDir, "stanza-sort")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	enc := gob.NewEncoder(bw)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return f.Name(), err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	return f.Name(), f.Close()
+}
+
+// merge merges the sorted runs into w.
+func merge(w *Writer, runs []string, cmp *comparer) error {
+	h := &runHeap{cmp: cmp}
+	defer func() {
+		for _, rn := range h.runs {
+			rn.f.Close()
+		}
+	}()
+	for i, name := range runs {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		rn := &run{f: f, dec: gob.NewDecoder(bufio.NewReader(f)), i: i}
+		ok, err := rn.next()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if !ok {
+			f.Close()
+			continue
+		}
+		h.runs = append(h.runs, rn)
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		rn := h.runs[0]
+		if err := w.WriteRecord(rn.rec); err != nil {
+			return err
+		}
+		ok, err := rn.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+			continue
+		}
+		rn.f.Close()
+		heap.Pop(h)
+	}
+	return w.Flush()
+}
+
+// A run is a temporary file with sorted records.
+type run struct {
+	f   *os.File
+	dec *gob.Decoder
+	i   int // index of the run
+	rec Record
+}
+
+// next reads the next record of a run.
+func (rn *run) next() (bool, error) {
+	var rec Record
+	err := rn.dec.Decode(&rec)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	rn.rec = rec
+	return true, nil
+}
+
+// runHeap is a heap of runs, ordered by their current record.
+type runHeap struct {
+	runs []*run
+	cmp  *comparer
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+func (h *runHeap) Less(i, j int) bool {
+	if c := h.cmp.compare(h.runs[i].rec, h.runs[j].rec); c != 0 {
+		return c < 0
+	}
+	return h.runs[i].i < h.runs[j].i
+}
+func (h *runHeap) Swap(i, j int)      { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap) Push(x interface{}) { h.runs = append(h.runs, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	rn := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return rn
+}
+
+// A comparer compares records using a list of sort keys.
+type comparer struct {
+	keys []SortKey
+	coll []*collate.Collator
+}
+
+func newComparer(keys []SortKey) *comparer {
+	c := &comparer{keys: keys, coll: make([]*collate.Collator, len(keys))}
+	for i, k := range keys {
+		if k.Mode == LocaleSort {
+			c.coll[i] = collate.New(language.Make(k.Locale))
+		}
+	}
+	return c
+}
+
+// compare compares two records.
+func (c *comparer) compare(a, b Record) int {
+	for i, k := range c.keys {
+		va, _ := a.Get(k.Field)
+		vb, _ := b.Get(k.Field)
+		var r int
+		switch k.Mode {
+		case NumericSort:
+			r = numericCompare(va, vb)
+		case NaturalSort:
+			r = naturalCompare(va, vb)
+		case LocaleSort:
+			r = c.coll[i].CompareString(va, vb)
+		default:
+			r = strings.Compare(va, vb)
+		}
+		if r == 0 {
+			continue
+		}
+		if k.Desc {
+			return -r
+		}
+		return r
+	}
+	return 0
+}
+
+// numericCompare compares two values as numbers.
+func numericCompare(a, b string) int {
+	fa, ea := parseNumber(a)
+	fb, eb := parseNumber(b)
+	switch {
+	case ea != nil && eb != nil:
+		return strings.Compare(a, b)
+	case ea != nil:
+		return -1
+	case eb != nil:
+		return 1
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+	return 0
+}
+
+// naturalCompare compares two values, comparing the sequences of digits
+// as numbers.
+func naturalCompare(a, b string) int {
+	for len(a) > 0 && len(b) > 0 {
+		da, db := isDigit(a[0]), isDigit(b[0])
+		if !da || !db {
+			if a[0] != b[0] {
+				if a[0] < b[0] {
+					return -1
+				}
+				return 1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		na, nb := digits(a), digits(b)
+		a, b = a[len(na):], b[len(nb):]
+		na, nb = strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+		if len(na) != len(nb) {
+			if len(na) < len(nb) {
+				return -1
+			}
+			return 1
+		}
+		if r := strings.Compare(na, nb); r != 0 {
+			return r
+		}
+	}
+	return len(a) - len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digits returns the leading digits of s.
+func digits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2017, J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package stanza
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var sortBlob = `name: ñandú
+id: sp10
+size: 140
+%%
+name: nutria
+id: sp2
+size: 60
+%%
+name: oso
+id: sp1
+size: 180
+%%
+name: Ocelote
+id: sp20
+size: 60
+%%
+name: zorro
+id: sp3
+%%
+`
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		keys []SortKey
+		want string
+	}{
+		{[]SortKey{{Field: "name"}}, "Ocelote,nutria,oso,zorro,ñandú"},
+		{[]SortKey{{Field: "name", Mode: LocaleSort, Locale: "es"}}, "nutria,ñandú,Ocelote,oso,zorro"},
+		{[]SortKey{{Field: "id", Mode: NaturalSort}}, "oso,nutria,zorro,ñandú,Ocelote"},
+		{[]SortKey{{Field: "size", Mode: NumericSort, Desc: true}, {Field: "id", Desc: true}}, "oso,ñandú,Ocelote,nutria,zorro"},
+		{[]SortKey{{Field: "size", Mode: NumericSort}}, "zorro,nutria,Ocelote,ñandú,oso"},
+	}
+	for _, test := range tests {
+		for _, size := range []int{0, 2} {
+			out := &bytes.Buffer{}
+			s := &Sorter{Keys: test.keys, RunSize: size}
+			if err := s.Sort(NewWriter(out), NewReader(strings.NewReader(sortBlob))); err != nil {
+				t.Fatalf("sort: %v: %v", test.keys, err)
+			}
+			recs, err := readAll(NewReader(out))
+			if err != nil {
+				t.Fatalf("sort: %v: %v", test.keys, err)
+			}
+			var names []string
+			for _, rec := range recs {
+				names = append(names, rec["name"])
+			}
+			if got := strings.Join(names, ","); got != test.want {
+				t.Errorf("sort: %v: run size %d: found %s, want %s", test.keys, size, got, test.want)
+			}
+		}
+	}
+}
+
+func TestNumericSort(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.UseLF = true
+	r := NewReader(strings.NewReader("v: 3\n%%\nv: nan\n%%\nv: 1\n%%\nv: Inf\n%%\nv: 2\n%%\n"))
+	if err := Sort(w, r, SortKey{Field: "v", Mode: NumericSort}); err != nil {
+		t.Fatalf("numeric sort: %v", err)
+	}
+	recs, err := readAll(NewReader(out))
+	if err != nil {
+		t.Fatalf("numeric sort: %v", err)
+	}
+	var vs []string
+	for _, rec := range recs {
+		vs = append(vs, rec["v"])
+	}
+	if got, want := strings.Join(vs, ","), "Inf,nan,1,2,3"; got != want {
+		t.Errorf("numeric sort: found %s, want %s", got, want)
+	}
+}
+
+var debBlob = `Package: zsh
+Description: shell with lots of features
+ Zsh is a UNIX command interpreter.
+ .
+   - indented line
+ .
+ Last paragraph.
+
+Package: bash
+Description: GNU Bourne Again SHell
+ Bash is an sh-compatible command language interpreter.
+ .
+ It includes   repeated   spaces.
+`
+
+func TestSortRuns(t *testing.T) {
+	var outs []string
+	for _, size := range []int{0, 1} {
+		r := NewReader(strings.NewReader(debBlob))
+		r.Dialect = Debian
+		out := &bytes.Buffer{}
+		w := NewWriter(out)
+		w.UseLF = true
+		w.Dialect = Debian
+		s := &Sorter{Keys: []SortKey{{Field: "package"}}, RunSize: size}
+		if err := s.Sort(w, r); err != nil {
+			t.Fatalf("sort runs: run size %d: %v", size, err)
+		}
+		outs = append(outs, out.String())
+	}
+	if !strings.HasPrefix(outs[0], "package: bash\n") {
+		t.Errorf("sort runs: found %q", outs[0])
+	}
+	if outs[1] != outs[0] {
+		t.Errorf("sort runs: found %q, want %q", outs[1], outs[0])
+	}
+}
@@ -139,3 +139,336 @@ func TestWrite(t *testing.T) {
 		t.Errorf("write: expecting 4 records, found: %d", i)
 	}
 }
+
+func TestComputed(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.SetFields([]string{"id", "common", "label", "kind"})
+	w.SetComputed("id", Counter(1))
+	w.SetComputed("label", Concat(" / ", "id", "common"))
+	w.SetComputed("kind", Constant("country"))
+	recs := []map[string]string{
+		{"common": "Argentina"},
+		{"common": "Chile", "kind": "republic"},
+	}
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			t.Errorf("computed: %v", err)
+		}
+	}
+	w.Flush()
+	if _, ok := recs[0]["id"]; ok {
+		t.Errorf("computed: input record modified")
+	}
+
+	want := []map[string]string{
+		{"id": "1", "common": "Argentina", "label": "1 / Argentina", "kind": "country"},
+		{"id": "2", "common": "Chile", "label": "2 / Chile", "kind": "republic"},
+	}
+	r := NewReader(strings.NewReader(out.String()))
+	for _, wr := range want {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("computed: reading error: %v", err)
+		}
+		for f, v := range wr {
+			if rec[f] != v {
+				t.Errorf("computed: field %q: found %q, want %q", f, rec[f], v)
+			}
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	data := "Name: Argentina\n%%\nname: Chile\nname: Chile\n%%\n: Peru\n%%\nname: Uruguay\ncapital: Montevideo\n"
+	want := []int{1, 4, 6, 8}
+	r := NewReader(strings.NewReader(data))
+	r.Strict = true
+	var lines []int
+	for {
+		_, err := r.Read()
+		if err == nil {
+			continue
+		}
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		pe, ok := errors.Cause(err).(*ParseError)
+		if !ok {
+			t.Fatalf("strict: unexpected error: %v", err)
+		}
+		lines = append(lines, pe.Line)
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("strict: found errors at lines %v, want %v", lines, want)
+	}
+	for i, l := range want {
+		if lines[i] != l {
+			t.Errorf("strict: found error at line %d, want %d", lines[i], l)
+		}
+	}
+}
+
+func TestDebian(t *testing.T) {
+	data := `Package: stanza
+Status: install ok installed
+Description: list records
+ Stanza reads and writes
+ .
+ records in a list format.
+
+Package: recutils
+Version: 1.8
+`
+	r := NewReader(strings.NewReader(data))
+	r.Dialect = Debian
+	var recs []map[string]string
+	for {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("debian: reading error: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("debian: expecting 2 records, found: %d", len(recs))
+	}
+	if d := recs[0]["description"]; d != "list records\nStanza reads and writes\n\nrecords in a list format." {
+		t.Errorf("debian: field %q: found %q", "description", d)
+	}
+	if v := recs[1]["version"]; v != "1.8" {
+		t.Errorf("debian: field %q: found %q", "version", v)
+	}
+
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.UseLF = true
+	w.Dialect = Debian
+	w.SetFields([]string{"package", "status", "version", "description"})
+	for _, rec := range recs {
+		w.Write(rec)
+	}
+	w.Flush()
+	if got := strings.ToLower(out.String()); got != strings.ToLower(data)+"\n" {
+		t.Errorf("debian: written:\n%s", out.String())
+	}
+}
+
+func TestDialect(t *testing.T) {
+	data := `; country data
+Name = Argentina
+; a comment between fields
+ISO Code = AR
+--
+Name = Chile
+ISO Code = CL
+--
+`
+	d := Dialect{
+		Separator:  '=',
+		Comments:   []string{";"},
+		Terminator: '-',
+		KeepCase:   true,
+		KeepSpaces: true,
+	}
+	r := NewReader(strings.NewReader(data))
+	r.Dialect = d
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.UseLF = true
+	w.Dialect = d
+	if err := w.SetFields([]string{"Name", "ISO Code"}); err != nil {
+		t.Fatalf("dialect: %v", err)
+	}
+	i := 0
+	for {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("dialect: reading error: %v", err)
+		}
+		if len(rec) != 2 {
+			t.Errorf("dialect: expecting 2 fields, found: %d", len(rec))
+		}
+		if _, ok := rec["ISO Code"]; !ok {
+			t.Errorf("dialect: field %q not found", "ISO Code")
+		}
+		w.Write(rec)
+		i++
+	}
+	if i != 2 {
+		t.Errorf("dialect: expecting 2 records, found: %d", i)
+	}
+	w.Flush()
+	want := "Name=\tArgentina\nISO Code= AR\n--\nName=\tChile\nISO Code= CL\n--\n"
+	if out.String() != want {
+		t.Errorf("dialect: written %q, want %q", out.String(), want)
+	}
+}
+
+func TestSpelling(t *testing.T) {
+	r := NewReader(strings.NewReader(blob))
+	rec, err := r.ReadRecord()
+	if err != nil {
+		t.Fatalf("spelling: reading error: %v", err)
+	}
+	if sp := r.Spelling("iso3166"); sp != "ISO3166" {
+		t.Errorf("spelling: found %q, want %q", sp, "ISO3166")
+	}
+	if rec[2].Name != "iso3166" || rec[2].Spelling != "ISO3166" {
+		t.Errorf("spelling: field %v", rec[2])
+	}
+	if v, ok := rec.Lookup("Iso3166"); !ok || v != "AR" {
+		t.Errorf("spelling: lookup %q: found %q", "Iso3166", v)
+	}
+
+	out := &bytes.Buffer{}
+	w := NewWriter(out)
+	w.UseLF = true
+	w.SetFields([]string{"common", "iso3166"})
+	w.SetSpelling(r.Spellings())
+	w.WriteRecord(rec)
+	w.Flush()
+	if want := "Common: Argentina\nISO3166: AR\n%%\n"; out.String() != want {
+		t.Errorf("spelling: written %q, want %q", out.String(), want)
+	}
+
+	// the spelling of a record field has precedence
+	out.Reset()
+	w = NewWriter(out)
+	w.UseLF = true
+	w.SetSpelling(r.Spellings())
+	w.WriteRecord(Record{
+		{Name: "common", Value: "Chile", Spelling: "COMMON"},
+		{Name: "iso3166", Value: "CL", Spelling: "ISO-3166"},
+		{Name: "capital", Value: "Santiago"},
+	})
+	w.Flush()
+	if want := "COMMON: Chile\nISO3166: CL\nCapital: Santiago\n%%\n"; out.String() != want {
+		t.Errorf("spelling: written %q, want %q", out.String(), want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	data := "name: Argen\xfftina\n%%\nname: Chile\ncapital: San\x07tiago\n%%\nname: Peru\n%%\n"
+	want := []ParseError{
+		{Line: 1, Column: 12, Err: ErrInvalidUTF8},
+		{Line: 4, Column: 13, Err: ErrControl},
+	}
+
+	r := NewReader(strings.NewReader(data))
+	r.Check = Warn
+	recs, err := readAll(r)
+	if err != nil {
+		t.Fatalf("check: warn: %v", err)
+	}
+	if len(recs) != 3 {
+		t.Errorf("check: warn: expecting 3 records, found: %d", len(recs))
+	}
+	ws := r.Warnings()
+	if len(ws) != len(want) {
+		t.Fatalf("check: warn: found %d warnings, want %d", len(ws), len(want))
+	}
+	for i, w := range ws {
+		if *w.(*ParseError) != want[i] {
+			t.Errorf("check: warn: found %v, want %v", w, &want[i])
+		}
+	}
+
+	r = NewReader(strings.NewReader(data))
+	r.Check = Fail
+	var errs []ParseError
+	n := 0
+	for {
+		_, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, *errors.Cause(err).(*ParseError))
+			continue
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("check: fail: expecting 1 valid record, found: %d", n)
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("check: fail: found %d errors, want %d", len(errs), len(want))
+	}
+	for i, e := range errs {
+		if e != want[i] {
+			t.Errorf("check: fail: found %v, want %v", &e, &want[i])
+		}
+	}
+}
+
+// failWriter is a writer that fails after n bytes are written.
+type failWriter struct {
+	n      int
+	closed bool
+}
+
+func (fw *failWriter) Write(p []byte) (int, error) {
+	if len(p) > fw.n {
+		n := fw.n
+		fw.n = 0
+		return n, errors.New("disk full")
+	}
+	fw.n -= len(p)
+	return len(p), nil
+}
+
+func (fw *failWriter) Close() error {
+	fw.closed = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	recs, err := readAll(NewReader(strings.NewReader(blob)))
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	fw := &failWriter{n: 100}
+	w := NewWriter(fw)
+	for i := 0; i < 100; i++ {
+		if err = w.Write(recs[i%len(recs)]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatalf("close: expecting a writing error")
+	}
+	if w.Error() != err {
+		t.Errorf("close: error %v, want %v", w.Error(), err)
+	}
+	if e := w.WriteRecord(Record{{Name: "name", Value: "Chile"}}); e != err {
+		t.Errorf("close: write after error: %v, want %v", e, err)
+	}
+	if e := w.Close(); e != err {
+		t.Errorf("close: %v, want %v", e, err)
+	}
+	if !fw.closed {
+		t.Errorf("close: underlying writer not closed")
+	}
+}
+
+// readAll reads all the records from r.
+func readAll(r *Reader) ([]map[string]string, error) {
+	var recs []map[string]string
+	for {
+		rec, err := r.Read()
+		if errors.Cause(err) == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+}
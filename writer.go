@@ -20,20 +20,67 @@ import (
 //
 // By default only fields with some content will be printed. If ForceEmpty is
 // true, then fields without content will be also printed.
+//
+// Computed fields, set with SetComputed, are filled before a record is
+// written.
+//
+// By default lines are terminated with "\r\n". If UseLF is true, lines will
+// be terminated with "\n".
+//
+// Dialect defines the variant of the format to be written.
+//
+// If BOM is true, a byte order mark (U+FEFF) will be written before the
+// first record.
+//
+// Normalize is the Unicode normalization applied to the written field names
+// and field contents. If FoldCase is true, field names are written using
+// full Unicode case folding rules.
+//
+// If an error occurs writing to the underlying io.Writer, no more records
+// will be written, and the error will be returned by all the following
+// calls to Write, WriteRecord, Flush and Close.
 type Writer struct {
-	ForceEmpty bool // write empty fields
-	fields     []string
-	w          *bufio.Writer
-	fc         int // field count (used in writing)
+	ForceEmpty bool          // write empty fields
+	UseLF      bool          // use \n as line terminator
+	Dialect    Dialect       // variant of the format
+	BOM        bool          // write a byte order mark
+	Normalize  Normalization // normalization of names and contents
+	FoldCase   bool          // fold the case of field names
+
+	started  bool // a record was already written
+	fields   []string
+	spell    map[string]string
+	computed []computedField
+	w        *bufio.Writer
+	c        io.Closer // closer of the underlying writer
+	fl       flusher   // flusher of the underlying writer
+	err      error     // first writing error
+	fc       int       // field count (used in writing)
+}
+
+// computedField is a field filled by a Compute function.
+type computedField struct {
+	name string
+	fn   Compute
 }
 
-// NewWriter returns a new Writer that writes to w.
+// flusher is a writer with its own buffer (e.g. a gzip.Writer).
+type flusher interface {
+	Flush() error
+}
+
+// NewWriter returns a new Writer that writes to w. If w is an io.Closer, it
+// will be closed when the Writer is closed. If w has a Flush method, it
+// will be called when the Writer is flushed.
 func NewWriter(w io.Writer) *Writer {
-	return &Writer{w: bufio.NewWriter(w)}
+	c, _ := w.(io.Closer)
+	fl, _ := w.(flusher)
+	return &Writer{w: bufio.NewWriter(w), c: c, fl: fl}
 }
 
-// SetFields sets the fields to be written. The fields must be unique, in
-// lower cases and without spaces. In the following records, the fields will
+// SetFields sets the fields to be written. The fields must be unique, and in
+// the canonical form of the writer dialect (by default, in lower cases and
+// without spaces). In the following records, the fields will
 // be printed in the order of the input slice. This is important if file
 // serialization should have fields on a given order (e.g. gor comparsion with
 // 'diff').
@@ -41,7 +88,7 @@ func (w *Writer) SetFields(fields []string) error {
 	ok := make(map[string]bool)
 	var nf []string
 	for _, f := range fields {
-		cp := strings.ToLower(strings.Join(strings.Fields(f), "-"))
+		cp := w.Dialect.fieldName(f)
 		if len(cp) == 0 {
 			continue
 		}
@@ -64,40 +111,264 @@ func (w *Writer) Fields() []string {
 	return w.fields
 }
 
-// Flush writes any bufferend data to the underlying io.Writer.
-func (w *Writer) Flush() error {
-	w.w.Flush()
-	if _, err := w.w.Write(nil); err != nil {
-		return errors.Wrap(err, "stanza: Flush")
+// SetSpelling sets the spelling used to write the field names. Each key of
+// the map is a field name, and the value is the spelling to be written
+// (e.g. as returned by Reader.Spellings). A spelling is only used if it is
+// equivalent to the field name in the writer dialect. Once a spelling is
+// set, WriteRecord prefers the spelling of each record field, if known.
+func (w *Writer) SetSpelling(spell map[string]string) {
+	w.spell = make(map[string]string, len(spell))
+	for f, sp := range spell {
+		if w.Dialect.fieldName(sp) != f {
+			continue
+		}
+		w.spell[f] = sp
+	}
+}
+
+// SetComputed sets a field whose content is calculated by fn when a record
+// is written, if the record does not have a content for the field. Computed
+// fields are filled in the order in which they were set, so a computed
+// field can use the value of a previous one. The field name must be in the
+// canonical form of the writer dialect. If fn is nil, the computed field is
+// removed.
+func (w *Writer) SetComputed(field string, fn Compute) error {
+	if cp := w.Dialect.fieldName(field); len(cp) == 0 || cp != field {
+		return errors.Errorf("stanza: SetComputed: field %q is not valid", field)
+	}
+	for i, c := range w.computed {
+		if c.name != field {
+			continue
+		}
+		if fn == nil {
+			w.computed = append(w.computed[:i], w.computed[i+1:]...)
+			return nil
+		}
+		w.computed[i].fn = fn
+		return nil
+	}
+	if fn != nil {
+		w.computed = append(w.computed, computedField{name: field, fn: fn})
 	}
 	return nil
 }
 
+// Flush writes any bufferend data to the underlying io.Writer, and flushes
+// the underlying writer, if it has a Flush method.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.err = errors.Wrap(err, "stanza: Flush")
+		return w.err
+	}
+	if w.fl == nil {
+		return nil
+	}
+	if err := w.fl.Flush(); err != nil {
+		w.err = errors.Wrap(err, "stanza: Flush")
+	}
+	return w.err
+}
+
+// Error reports the first error that has occurred during a previous Write,
+// WriteRecord, Flush or Close.
+func (w *Writer) Error() error {
+	return w.err
+}
+
+// Close flushes any buffered data and closes the underlying io.Writer, if
+// it is an io.Closer. It returns the first error that has occurred in the
+// writer.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.c == nil {
+		return err
+	}
+	c := w.c
+	w.c = nil
+	if e := c.Close(); e != nil && err == nil {
+		w.err = errors.Wrap(e, "stanza: Close")
+		err = w.err
+	}
+	return err
+}
+
 // Write writes a single record to w. A record is a map in which each entry
 // represents the content of the field indicated by the key.
 func (w *Writer) Write(record map[string]string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.write(record); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// write writes a record map.
+func (w *Writer) write(record map[string]string) error {
+	if err := w.start(); err != nil {
+		return errors.Wrap(err, "stanza: Write")
+	}
 	w.fc = 0
+	record = w.compute(record)
 	if len(w.fields) == 0 {
 		return w.writeMap(record)
 	}
 	for _, f := range w.fields {
-		if err := w.writeField(f, record[f]); err != nil {
+		if err := w.writeField(f, "", record[f]); err != nil {
 			return errors.Wrap(err, "stanza: Write: writing record")
 		}
 	}
-	if w.fc > 0 {
-		if _, err := w.w.WriteString("%%\r\n"); err != nil {
-			return errors.Wrap(err, "stanza: Write: writing end-of-record")
+	if err := w.endRecord(); err != nil {
+		return errors.Wrap(err, "stanza: Write: writing end-of-record")
+	}
+	return nil
+}
+
+// WriteRecord writes a single record to w. If fields are defined, repeated
+// fields will be written together, in the record order, at the position of
+// the field. If no fields are defined, the fields will be written in the
+// record order.
+func (w *Writer) WriteRecord(record Record) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.writeRecord(record); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// writeRecord writes a record.
+func (w *Writer) writeRecord(record Record) error {
+	if err := w.start(); err != nil {
+		return errors.Wrap(err, "stanza: WriteRecord")
+	}
+	w.fc = 0
+	record = w.computeRecord(record)
+	if len(w.fields) == 0 {
+		for _, f := range record {
+			name := w.Dialect.fieldName(f.Name)
+			if len(name) == 0 {
+				continue
+			}
+			if err := w.writeField(name, f.Spelling, f.Value); err != nil {
+				return errors.Wrap(err, "stanza: WriteRecord: writing record")
+			}
 		}
+	} else {
+		for _, f := range w.fields {
+			n := 0
+			for _, rf := range record {
+				if rf.Name != f {
+					continue
+				}
+				n++
+				if err := w.writeField(f, rf.Spelling, rf.Value); err != nil {
+					return errors.Wrap(err, "stanza: WriteRecord: writing record")
+				}
+			}
+			if n > 0 {
+				continue
+			}
+			if err := w.writeField(f, "", ""); err != nil {
+				return errors.Wrap(err, "stanza: WriteRecord: writing record")
+			}
+		}
+	}
+	if err := w.endRecord(); err != nil {
+		return errors.Wrap(err, "stanza: WriteRecord: writing end-of-record")
 	}
 	return nil
 }
 
+// start writes the byte order mark, if required, before the first record.
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if !w.BOM {
+		return nil
+	}
+	_, err := w.w.WriteRune('\uFEFF')
+	return err
+}
+
+// endRecord writes the end-of-record mark, if at least one field was
+// written.
+func (w *Writer) endRecord() error {
+	if w.fc == 0 {
+		return nil
+	}
+	if w.Dialect.BlankSeparator && w.Dialect.Terminator == 0 {
+		_, err := w.w.WriteString(w.eol())
+		return err
+	}
+	t := string(w.Dialect.terminator())
+	_, err := w.w.WriteString(t + t + w.eol())
+	return err
+}
+
+// compute returns a record with the computed fields filled. If there are
+// computed fields, the returned record is a copy of the original.
+func (w *Writer) compute(record map[string]string) map[string]string {
+	if len(w.computed) == 0 {
+		return record
+	}
+	rec := make(map[string]string, len(record)+len(w.computed))
+	for f, v := range record {
+		rec[f] = v
+	}
+	for _, c := range w.computed {
+		if len(strings.TrimSpace(rec[c.name])) > 0 {
+			continue
+		}
+		rec[c.name] = c.fn(rec)
+	}
+	return rec
+}
+
+// computeRecord returns a record with the computed fields filled. If there
+// are computed fields, the returned record is a copy of the original.
+func (w *Writer) computeRecord(record Record) Record {
+	if len(w.computed) == 0 {
+		return record
+	}
+	rec := make(Record, len(record), len(record)+len(w.computed))
+	copy(rec, record)
+	m := rec.Map()
+	for _, c := range w.computed {
+		if len(strings.TrimSpace(m[c.name])) > 0 {
+			continue
+		}
+		v := c.fn(m)
+		m[c.name] = v
+		set := false
+		for i := range rec {
+			if rec[i].Name == c.name {
+				rec[i].Value = v
+				set = true
+				break
+			}
+		}
+		if !set {
+			rec = append(rec, Field{Name: c.name, Value: v})
+		}
+	}
+	return rec
+}
+
 // writeMap writes a map (in the default order) to a file.
 func (w *Writer) writeMap(rec map[string]string) error {
 	ok := make(map[string]bool)
 	for f, v := range rec {
-		f = strings.ToLower(strings.Join(strings.Fields(f), "-"))
+		f = w.Dialect.fieldName(f)
 		if len(f) == 0 {
 			continue
 		}
@@ -105,52 +376,73 @@ func (w *Writer) writeMap(rec map[string]string) error {
 			continue
 		}
 		ok[f] = true
-		if err := w.writeField(f, v); err != nil {
+		if err := w.writeField(f, "", v); err != nil {
 			return errors.Wrap(err, "stanza: Write: writing record")
 		}
 	}
-	if w.fc > 0 {
-		if _, err := w.w.WriteString("%%\r\n"); err != nil {
-			return errors.Wrap(err, "stanza: Write: writing end-of-record")
-		}
+	if err := w.endRecord(); err != nil {
+		return errors.Wrap(err, "stanza: Write: writing end-of-record")
 	}
 	return nil
 }
 
-// writeField writes a field into a file.
-func (w *Writer) writeField(f, v string) (err error) {
-	v = strings.TrimSpace(v)
+// writeField writes a field into a file. If spelling is kept, and sp is a
+// valid spelling of the field, sp is used as the field name.
+func (w *Writer) writeField(f, sp, v string) (err error) {
+	v = w.Normalize.apply(strings.TrimSpace(v))
+	if w.spell != nil && len(sp) > 0 && w.Dialect.fieldName(sp) == f {
+		f = sp
+	} else if sp, ok := w.spell[f]; ok {
+		f = sp
+	} else if w.FoldCase {
+		f = foldCase(f)
+	}
+	f = w.Normalize.apply(f)
 	if len(v) == 0 {
 		if !w.ForceEmpty {
 			return nil
 		}
-		if _, err = w.w.WriteString(f + "\r\n"); err != nil {
+		if _, err = w.w.WriteString(f + w.eol()); err != nil {
 			return err
 		}
 		w.fc++
 		return nil
 	}
-	if len(f) < 6 {
-		_, err = w.w.WriteString(f + ":\t")
+	sep := string(w.Dialect.separator())
+	indent := "\t"
+	if w.Dialect.BlankSeparator {
+		indent = " "
+		_, err = w.w.WriteString(f + sep + " ")
+	} else if len(f) < 6 {
+		_, err = w.w.WriteString(f + sep + "\t")
 	} else {
-		_, err = w.w.WriteString(f + ": ")
+		_, err = w.w.WriteString(f + sep + " ")
 	}
 	if err != nil {
 		return err
 	}
 
-	for _, r1 := range v {
-		switch r1 {
-		case '\r':
-		case '\n':
-			_, err = w.w.WriteString("\r\n\t")
-		default:
-			_, err = w.w.WriteRune(r1)
+	v = strings.Replace(v, "\r", "", -1)
+	for i, ln := range strings.Split(v, "\n") {
+		if i > 0 {
+			w.w.WriteString(w.eol() + indent)
+			if w.Dialect.DotBlank && len(strings.TrimSpace(ln)) == 0 {
+				ln = "."
+			}
 		}
+		_, err = w.w.WriteString(ln)
 	}
-	if _, err = w.w.WriteString("\r\n"); err != nil {
+	if _, err = w.w.WriteString(w.eol()); err != nil {
 		return err
 	}
 	w.fc++
 	return nil
 }
+
+// eol returns the line terminator.
+func (w *Writer) eol() string {
+	if w.UseLF {
+		return "\n"
+	}
+	return "\r\n"
+}